@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"swapper/adminrpc"
+	"swapper/chain"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// adminServer implements adminrpc.AdminSwapperServer.
+type adminServer struct {
+	adminrpc.UnimplementedAdminSwapperServer
+}
+
+// pemEnv returns the PEM block in the environment variable name, with the
+// escaped newlines of single line values restored.
+func pemEnv(name string) []byte {
+	return []byte(strings.Replace(os.Getenv(name), "\\n", "\n", -1))
+}
+
+// adminTLSConfig requires the clients to present a certificate signed by
+// ADMIN_CLIENT_CA. The server presents ADMIN_TLS_CERT and ADMIN_TLS_KEY.
+func adminTLSConfig() (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(pemEnv("ADMIN_TLS_CERT"), pemEnv("ADMIN_TLS_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("ADMIN_TLS_CERT, ADMIN_TLS_KEY: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pemEnv("ADMIN_CLIENT_CA")) {
+		return nil, errors.New("ADMIN_CLIENT_CA: no certificate")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// serveAdmin serves the AdminSwapper service on ADMIN_LISTEN_ADDRESS, if
+// set, over mutual TLS.
+func serveAdmin() error {
+	address := os.Getenv("ADMIN_LISTEN_ADDRESS")
+	if address == "" {
+		return nil
+	}
+	tlsConfig, err := adminTLSConfig()
+	if err != nil {
+		return err
+	}
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.UnaryInterceptor(adminAuditInterceptor),
+	)
+	adminrpc.RegisterAdminSwapperServer(s, adminServer{})
+	go func() {
+		err := s.Serve(lis)
+		slog.Error("admin server stopped", "error", err)
+	}()
+	return nil
+}
+
+// operatorFromContext returns the common name of the client certificate of
+// the request in ctx.
+func operatorFromContext(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName
+}
+
+// adminAuditInterceptor logs every admin call with the operator making it.
+func adminAuditInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	slog.Info("admin call", "method", path.Base(info.FullMethod), "operator", operatorFromContext(ctx),
+		"code", status.Code(err))
+	return resp, err
+}
+
+// parseSwapState returns the swap state named s.
+func parseSwapState(s string) (swapState, error) {
+	for state := swapStateCreated; state <= swapStateExpired; state++ {
+		if state.String() == s {
+			return state, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown swap state %q", s)
+}
+
+func swapProto(s *swapRecord) *adminrpc.Swap {
+	p := &adminrpc.Swap{
+		Network:        s.network,
+		Hash:           s.hash,
+		SwapType:       s.typ.String(),
+		LockHeight:     s.lockHeight,
+		Script:         s.script,
+		Address:        s.address,
+		State:          s.state.String(),
+		Amount:         int64(s.pricing.amount),
+		ServiceFee:     int64(s.pricing.serviceFee),
+		FeeRate:        int64(s.pricing.feeRate),
+		QuoteId:        s.pricing.quoteID,
+		RefundAddress:  s.pricing.refundAddress,
+		Reconciliation: s.reconciliation.String(),
+		Created:        s.created.Unix(),
+		CredentialId:   s.credentialID,
+	}
+	if h, err := chainhash.NewHash(s.redeemTxid); err == nil {
+		p.RedeemTxid = h.String()
+	}
+	return p
+}
+
+func depositProto(d swapDeposit) *adminrpc.Deposit {
+	p := &adminrpc.Deposit{
+		Txid:        d.outPoint.Hash.String(),
+		Vout:        d.outPoint.Index,
+		Value:       int64(d.value),
+		BlockHeight: d.blockHeight,
+	}
+	if h, err := chainhash.NewHash(d.spendTxid); err == nil {
+		p.SpendTxid = h.String()
+		p.SpendPath = d.spendPath.String()
+	}
+	return p
+}
+
+// unspentUtxos returns the deposits whose spend isn't confirmed yet.
+func unspentUtxos(deposits []swapDeposit) []chain.Utxo {
+	var utxos []chain.Utxo
+	for _, d := range deposits {
+		if d.spendTxid == nil {
+			utxos = append(utxos, chain.Utxo{Value: d.value, BlockHeight: d.blockHeight, OutPoint: d.outPoint})
+		}
+	}
+	return utxos
+}
+
+// adminFeePerKw converts feeRate, in sat/vbyte, or returns the recommended
+// fee rate of net if feeRate is 0.
+func adminFeePerKw(ctx context.Context, net *chaincfg.Params, feeRate int64) (chainfee.SatPerKWeight, error) {
+	if feeRate < 0 {
+		return 0, status.Error(codes.InvalidArgument, "fee rate not valid")
+	}
+	if feeRate == 0 {
+		return recommendedFeePerKw(ctx, net)
+	}
+	return chainfee.SatPerKVByte(feeRate * 1000).FeePerKWeight(), nil
+}
+
+// swapFilterProto converts the filter of a ListSwaps request.
+func swapFilterProto(in *adminrpc.ListSwapsRequest) (swapFilter, error) {
+	f := swapFilter{
+		search: in.Search,
+		limit:  int(in.Limit),
+		offset: int(in.Offset),
+	}
+	if in.Network != "" {
+		n, err := getNetwork(in.Network)
+		if err != nil {
+			return f, status.Error(codes.InvalidArgument, err.Error())
+		}
+		f.network = n.params.Name
+	}
+	if in.State != "" {
+		s, err := parseSwapState(in.State)
+		if err != nil {
+			return f, status.Error(codes.InvalidArgument, err.Error())
+		}
+		f.state = &s
+	}
+	if in.Limit < 0 || in.Offset < 0 {
+		return f, status.Error(codes.InvalidArgument, "limit or offset not valid")
+	}
+	if in.CreatedAfter > 0 {
+		f.createdAfter = time.Unix(in.CreatedAfter, 0)
+	}
+	if in.CreatedBefore > 0 {
+		f.createdBefore = time.Unix(in.CreatedBefore, 0)
+	}
+	return f, nil
+}
+
+func (adminServer) ListSwaps(ctx context.Context, in *adminrpc.ListSwapsRequest) (*adminrpc.ListSwapsResponse, error) {
+	f, err := swapFilterProto(in)
+	if err != nil {
+		return nil, err
+	}
+	swaps, err := listSwaps(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	resp := &adminrpc.ListSwapsResponse{}
+	for _, s := range swaps {
+		resp.Swaps = append(resp.Swaps, swapProto(s))
+	}
+	return resp, nil
+}
+
+func (adminServer) GetSwap(ctx context.Context, in *adminrpc.GetSwapRequest) (*adminrpc.GetSwapResponse, error) {
+	n, err := getNetwork(in.Network)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	s, err := getSwap(ctx, n.params.Name, in.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, status.Error(codes.NotFound, "swap not found")
+	}
+	deposits, err := getSwapDeposits(ctx, n.params.Name, in.Hash)
+	if err != nil {
+		return nil, err
+	}
+	resp := &adminrpc.GetSwapResponse{Swap: swapProto(s)}
+	for _, d := range deposits {
+		resp.Deposits = append(resp.Deposits, depositProto(d))
+	}
+	return resp, nil
+}
+
+func (adminServer) Redeem(ctx context.Context, in *adminrpc.RedeemRequest) (*adminrpc.RedeemResponse, error) {
+	n, err := getNetwork(in.Network)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	address, err := btcutil.DecodeAddress(in.Address, n.params)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "address not valid: %v", err)
+	}
+	feePerKw, err := adminFeePerKw(ctx, n.params, in.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := redeem(ctx, n.params, in.Preimage, address, feePerKw)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("swap redeemed by operator", "network", n.params.Name, "txid", tx.TxHash(),
+		"operator", operatorFromContext(ctx))
+	return &adminrpc.RedeemResponse{Txid: tx.TxHash().String()}, nil
+}
+
+func (adminServer) Refund(ctx context.Context, in *adminrpc.RefundRequest) (*adminrpc.RefundResponse, error) {
+	n, err := getNetwork(in.Network)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	s, err := getSwap(ctx, n.params.Name, in.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, status.Error(codes.NotFound, "swap not found")
+	}
+	refundAddress := in.Address
+	if refundAddress == "" {
+		refundAddress = s.pricing.refundAddress
+	}
+	if refundAddress == "" {
+		return nil, status.Error(codes.InvalidArgument, "swap has no refund address")
+	}
+	address, err := btcutil.DecodeAddress(refundAddress, n.params)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "address not valid: %v", err)
+	}
+	deposits, err := getSwapDeposits(ctx, n.params.Name, in.Hash)
+	if err != nil {
+		return nil, err
+	}
+	feePerKw, err := adminFeePerKw(ctx, n.params, in.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := refundTx(s.typ, s.lockHeight, unspentUtxos(deposits), address, feePerKw)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	swapLogger(n.params.Name, in.Hash).Info("refund transaction built by operator", "address", refundAddress,
+		"operator", operatorFromContext(ctx))
+	return &adminrpc.RefundResponse{Tx: buf.Bytes(), LockHeight: int64(tx.LockTime)}, nil
+}
+
+func (adminServer) BumpFee(ctx context.Context, in *adminrpc.BumpFeeRequest) (*adminrpc.BumpFeeResponse, error) {
+	n, err := getNetwork(in.Network)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if in.FeeRate <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "fee rate required")
+	}
+	preimage, redeemAddress, err := getRedeem(ctx, n.params.Name, in.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if preimage == nil || redeemAddress == "" {
+		return nil, status.Error(codes.FailedPrecondition, "swap has no redeem transaction to replace")
+	}
+	address, err := btcutil.DecodeAddress(redeemAddress, n.params)
+	if err != nil {
+		return nil, err
+	}
+	deposits, err := getSwapDeposits(ctx, n.params.Name, in.Hash)
+	if err != nil {
+		return nil, err
+	}
+	feePerKw := chainfee.SatPerKVByte(in.FeeRate * 1000).FeePerKWeight()
+	tx, err := redeemUtxos(ctx, n.params, preimage, address, unspentUtxos(deposits), feePerKw)
+	if err != nil {
+		return nil, err
+	}
+	swapLogger(n.params.Name, in.Hash).Info("redeem fee bumped by operator", "txid", tx.TxHash(),
+		"fee_rate", in.FeeRate, "operator", operatorFromContext(ctx))
+	return &adminrpc.BumpFeeResponse{Txid: tx.TxHash().String()}, nil
+}
+
+func (adminServer) SetSwapState(ctx context.Context, in *adminrpc.SetSwapStateRequest) (*adminrpc.SetSwapStateResponse, error) {
+	n, err := getNetwork(in.Network)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	from, err := parseSwapState(in.From)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	to, err := parseSwapState(in.To)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if from == to {
+		return nil, status.Error(codes.InvalidArgument, "swap already in this state")
+	}
+	// The chain watcher sets the other states from the deposits and would
+	// undo the change.
+	if !to.final() {
+		return nil, status.Errorf(codes.InvalidArgument, "state %v not final", to)
+	}
+	if in.Reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason required")
+	}
+	s, err := getSwap(ctx, n.params.Name, in.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, status.Error(codes.NotFound, "swap not found")
+	}
+	ok, err := setSwapState(ctx, n.params.Name, in.Hash, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "swap not in state %v", from)
+	}
+	swapLogger(n.params.Name, in.Hash).Info("swap state set by operator", "from", from, "to", to,
+		"reason", in.Reason, "operator", operatorFromContext(ctx))
+	observeSwapTransition(n.params.Name, to, 1)
+
+	// Final swaps aren't watched anymore.
+	if !from.final() {
+		untrackSwap(n, openSwap{hash: s.hash, script: s.script, address: s.address, created: s.created})
+	}
+	return &adminrpc.SetSwapStateResponse{}, nil
+}
+
+// exportColumns are the CSV columns of ExportSwaps.
+var exportColumns = []string{
+	"created", "network", "hash", "swap_type", "lock_height", "address", "state", "amount",
+	"service_fee", "fee_rate", "quote_id", "refund_address", "reconciliation", "redeem_txid",
+}
+
+// exportCSV writes swaps in CSV, one line per swap after the header.
+func exportCSV(w io.Writer, swaps []*adminrpc.Swap) error {
+	cw := csv.NewWriter(w)
+	cw.Write(exportColumns)
+	for _, s := range swaps {
+		cw.Write([]string{
+			time.Unix(s.Created, 0).UTC().Format(time.RFC3339),
+			s.Network,
+			hex.EncodeToString(s.Hash),
+			s.SwapType,
+			strconv.FormatInt(s.LockHeight, 10),
+			s.Address,
+			s.State,
+			strconv.FormatInt(s.Amount, 10),
+			strconv.FormatInt(s.ServiceFee, 10),
+			strconv.FormatInt(s.FeeRate, 10),
+			hex.EncodeToString(s.QuoteId),
+			s.RefundAddress,
+			s.Reconciliation,
+			s.RedeemTxid,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (a adminServer) ExportSwaps(ctx context.Context, in *adminrpc.ExportSwapsRequest) (*adminrpc.ExportSwapsResponse, error) {
+	if in.Format != "json" && in.Format != "csv" {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown format %q", in.Format)
+	}
+	filter := in.Filter
+	if filter == nil {
+		filter = &adminrpc.ListSwapsRequest{}
+	}
+	// swapProto leaves the keys and the preimages out.
+	swaps, err := a.ListSwaps(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if in.Format == "json" {
+		data, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(swaps)
+		if err != nil {
+			return nil, err
+		}
+		return &adminrpc.ExportSwapsResponse{Data: data}, nil
+	}
+	var buf bytes.Buffer
+	if err := exportCSV(&buf, swaps.Swaps); err != nil {
+		return nil, err
+	}
+	return &adminrpc.ExportSwapsResponse{Data: buf.Bytes()}, nil
+}
+
+func (adminServer) ReloadConfig(ctx context.Context, in *adminrpc.ReloadConfigRequest) (*adminrpc.ReloadConfigResponse, error) {
+	slog.Info("config reload requested", "operator", operatorFromContext(ctx))
+	if err := reloadConfig(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &adminrpc.ReloadConfigResponse{}, nil
+}
@@ -0,0 +1,381 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.2
+// source: admin.proto
+
+package adminrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AdminSwapperClient is the client API for AdminSwapper service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminSwapperClient interface {
+	ListSwaps(ctx context.Context, in *ListSwapsRequest, opts ...grpc.CallOption) (*ListSwapsResponse, error)
+	GetSwap(ctx context.Context, in *GetSwapRequest, opts ...grpc.CallOption) (*GetSwapResponse, error)
+	// SetSwapState moves a swap to a final state, for example to expire a
+	// swap never funded or to close one settled out of band. The other
+	// states follow the chain and can't be set.
+	SetSwapState(ctx context.Context, in *SetSwapStateRequest, opts ...grpc.CallOption) (*SetSwapStateResponse, error)
+	// ExportSwaps returns the swaps as JSON or CSV. Neither keys nor
+	// preimages are exported.
+	ExportSwaps(ctx context.Context, in *ExportSwapsRequest, opts ...grpc.CallOption) (*ExportSwapsResponse, error)
+	// ReloadConfig reads the settings again, see CONFIG_FILE. The networks
+	// and listen addresses need a restart.
+	ReloadConfig(ctx context.Context, in *ReloadConfigRequest, opts ...grpc.CallOption) (*ReloadConfigResponse, error)
+	// Redeem claims the deposits of a swap with its preimage.
+	Redeem(ctx context.Context, in *RedeemRequest, opts ...grpc.CallOption) (*RedeemResponse, error)
+	// Refund builds the transaction returning the deposits of a swap to the
+	// payer once the timelock expired. The payer signs it.
+	Refund(ctx context.Context, in *RefundRequest, opts ...grpc.CallOption) (*RefundResponse, error)
+	// BumpFee replaces the redeem transaction of a swap with one paying a
+	// higher fee rate.
+	BumpFee(ctx context.Context, in *BumpFeeRequest, opts ...grpc.CallOption) (*BumpFeeResponse, error)
+}
+
+type adminSwapperClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminSwapperClient(cc grpc.ClientConnInterface) AdminSwapperClient {
+	return &adminSwapperClient{cc}
+}
+
+func (c *adminSwapperClient) ListSwaps(ctx context.Context, in *ListSwapsRequest, opts ...grpc.CallOption) (*ListSwapsResponse, error) {
+	out := new(ListSwapsResponse)
+	err := c.cc.Invoke(ctx, "/adminrpc.AdminSwapper/ListSwaps", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminSwapperClient) GetSwap(ctx context.Context, in *GetSwapRequest, opts ...grpc.CallOption) (*GetSwapResponse, error) {
+	out := new(GetSwapResponse)
+	err := c.cc.Invoke(ctx, "/adminrpc.AdminSwapper/GetSwap", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminSwapperClient) SetSwapState(ctx context.Context, in *SetSwapStateRequest, opts ...grpc.CallOption) (*SetSwapStateResponse, error) {
+	out := new(SetSwapStateResponse)
+	err := c.cc.Invoke(ctx, "/adminrpc.AdminSwapper/SetSwapState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminSwapperClient) ExportSwaps(ctx context.Context, in *ExportSwapsRequest, opts ...grpc.CallOption) (*ExportSwapsResponse, error) {
+	out := new(ExportSwapsResponse)
+	err := c.cc.Invoke(ctx, "/adminrpc.AdminSwapper/ExportSwaps", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminSwapperClient) ReloadConfig(ctx context.Context, in *ReloadConfigRequest, opts ...grpc.CallOption) (*ReloadConfigResponse, error) {
+	out := new(ReloadConfigResponse)
+	err := c.cc.Invoke(ctx, "/adminrpc.AdminSwapper/ReloadConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminSwapperClient) Redeem(ctx context.Context, in *RedeemRequest, opts ...grpc.CallOption) (*RedeemResponse, error) {
+	out := new(RedeemResponse)
+	err := c.cc.Invoke(ctx, "/adminrpc.AdminSwapper/Redeem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminSwapperClient) Refund(ctx context.Context, in *RefundRequest, opts ...grpc.CallOption) (*RefundResponse, error) {
+	out := new(RefundResponse)
+	err := c.cc.Invoke(ctx, "/adminrpc.AdminSwapper/Refund", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminSwapperClient) BumpFee(ctx context.Context, in *BumpFeeRequest, opts ...grpc.CallOption) (*BumpFeeResponse, error) {
+	out := new(BumpFeeResponse)
+	err := c.cc.Invoke(ctx, "/adminrpc.AdminSwapper/BumpFee", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminSwapperServer is the server API for AdminSwapper service.
+// All implementations must embed UnimplementedAdminSwapperServer
+// for forward compatibility
+type AdminSwapperServer interface {
+	ListSwaps(context.Context, *ListSwapsRequest) (*ListSwapsResponse, error)
+	GetSwap(context.Context, *GetSwapRequest) (*GetSwapResponse, error)
+	// SetSwapState moves a swap to a final state, for example to expire a
+	// swap never funded or to close one settled out of band. The other
+	// states follow the chain and can't be set.
+	SetSwapState(context.Context, *SetSwapStateRequest) (*SetSwapStateResponse, error)
+	// ExportSwaps returns the swaps as JSON or CSV. Neither keys nor
+	// preimages are exported.
+	ExportSwaps(context.Context, *ExportSwapsRequest) (*ExportSwapsResponse, error)
+	// ReloadConfig reads the settings again, see CONFIG_FILE. The networks
+	// and listen addresses need a restart.
+	ReloadConfig(context.Context, *ReloadConfigRequest) (*ReloadConfigResponse, error)
+	// Redeem claims the deposits of a swap with its preimage.
+	Redeem(context.Context, *RedeemRequest) (*RedeemResponse, error)
+	// Refund builds the transaction returning the deposits of a swap to the
+	// payer once the timelock expired. The payer signs it.
+	Refund(context.Context, *RefundRequest) (*RefundResponse, error)
+	// BumpFee replaces the redeem transaction of a swap with one paying a
+	// higher fee rate.
+	BumpFee(context.Context, *BumpFeeRequest) (*BumpFeeResponse, error)
+	mustEmbedUnimplementedAdminSwapperServer()
+}
+
+// UnimplementedAdminSwapperServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminSwapperServer struct {
+}
+
+func (UnimplementedAdminSwapperServer) ListSwaps(context.Context, *ListSwapsRequest) (*ListSwapsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSwaps not implemented")
+}
+func (UnimplementedAdminSwapperServer) GetSwap(context.Context, *GetSwapRequest) (*GetSwapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSwap not implemented")
+}
+func (UnimplementedAdminSwapperServer) SetSwapState(context.Context, *SetSwapStateRequest) (*SetSwapStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSwapState not implemented")
+}
+func (UnimplementedAdminSwapperServer) ExportSwaps(context.Context, *ExportSwapsRequest) (*ExportSwapsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportSwaps not implemented")
+}
+func (UnimplementedAdminSwapperServer) ReloadConfig(context.Context, *ReloadConfigRequest) (*ReloadConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadConfig not implemented")
+}
+func (UnimplementedAdminSwapperServer) Redeem(context.Context, *RedeemRequest) (*RedeemResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Redeem not implemented")
+}
+func (UnimplementedAdminSwapperServer) Refund(context.Context, *RefundRequest) (*RefundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refund not implemented")
+}
+func (UnimplementedAdminSwapperServer) BumpFee(context.Context, *BumpFeeRequest) (*BumpFeeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BumpFee not implemented")
+}
+func (UnimplementedAdminSwapperServer) mustEmbedUnimplementedAdminSwapperServer() {}
+
+// UnsafeAdminSwapperServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminSwapperServer will
+// result in compilation errors.
+type UnsafeAdminSwapperServer interface {
+	mustEmbedUnimplementedAdminSwapperServer()
+}
+
+func RegisterAdminSwapperServer(s grpc.ServiceRegistrar, srv AdminSwapperServer) {
+	s.RegisterService(&AdminSwapper_ServiceDesc, srv)
+}
+
+func _AdminSwapper_ListSwaps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSwapsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminSwapperServer).ListSwaps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminrpc.AdminSwapper/ListSwaps",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminSwapperServer).ListSwaps(ctx, req.(*ListSwapsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminSwapper_GetSwap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminSwapperServer).GetSwap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminrpc.AdminSwapper/GetSwap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminSwapperServer).GetSwap(ctx, req.(*GetSwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminSwapper_SetSwapState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetSwapStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminSwapperServer).SetSwapState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminrpc.AdminSwapper/SetSwapState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminSwapperServer).SetSwapState(ctx, req.(*SetSwapStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminSwapper_ExportSwaps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportSwapsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminSwapperServer).ExportSwaps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminrpc.AdminSwapper/ExportSwaps",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminSwapperServer).ExportSwaps(ctx, req.(*ExportSwapsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminSwapper_ReloadConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminSwapperServer).ReloadConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminrpc.AdminSwapper/ReloadConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminSwapperServer).ReloadConfig(ctx, req.(*ReloadConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminSwapper_Redeem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminSwapperServer).Redeem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminrpc.AdminSwapper/Redeem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminSwapperServer).Redeem(ctx, req.(*RedeemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminSwapper_Refund_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminSwapperServer).Refund(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminrpc.AdminSwapper/Refund",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminSwapperServer).Refund(ctx, req.(*RefundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminSwapper_BumpFee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BumpFeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminSwapperServer).BumpFee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminrpc.AdminSwapper/BumpFee",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminSwapperServer).BumpFee(ctx, req.(*BumpFeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminSwapper_ServiceDesc is the grpc.ServiceDesc for AdminSwapper service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminSwapper_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "adminrpc.AdminSwapper",
+	HandlerType: (*AdminSwapperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListSwaps",
+			Handler:    _AdminSwapper_ListSwaps_Handler,
+		},
+		{
+			MethodName: "GetSwap",
+			Handler:    _AdminSwapper_GetSwap_Handler,
+		},
+		{
+			MethodName: "SetSwapState",
+			Handler:    _AdminSwapper_SetSwapState_Handler,
+		},
+		{
+			MethodName: "ExportSwaps",
+			Handler:    _AdminSwapper_ExportSwaps_Handler,
+		},
+		{
+			MethodName: "ReloadConfig",
+			Handler:    _AdminSwapper_ReloadConfig_Handler,
+		},
+		{
+			MethodName: "Redeem",
+			Handler:    _AdminSwapper_Redeem_Handler,
+		},
+		{
+			MethodName: "Refund",
+			Handler:    _AdminSwapper_Refund_Handler,
+		},
+		{
+			MethodName: "BumpFee",
+			Handler:    _AdminSwapper_BumpFee_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	credentialIDLen     = 16
+	credentialSecretLen = 32
+)
+
+// credential is an issued API key together with its caveats.
+type credential struct {
+	id   []byte
+	name string
+	// secretHash is sha256 of the secret part of the key.
+	secretHash []byte
+	// methods are the RPC method names allowed, all if empty.
+	methods []string
+	// maxAmount caps the amount of the swaps created, 0 for no cap.
+	maxAmount btcutil.Amount
+	// expiry is when the key stops working, zero for never.
+	expiry  time.Time
+	revoked bool
+}
+
+// allows reports whether c may call the RPC method.
+func (c *credential) allows(method string) bool {
+	if len(c.methods) == 0 {
+		return true
+	}
+	for _, m := range c.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAmount checks a swap of amount, 0 if unknown, against the amount
+// caveat of c.
+func (c *credential) checkAmount(amount btcutil.Amount) error {
+	if c == nil || c.maxAmount == 0 {
+		return nil
+	}
+	if amount == 0 {
+		return status.Error(codes.PermissionDenied, "credential requires the swap amount")
+	}
+	if amount > c.maxAmount {
+		return status.Errorf(codes.PermissionDenied, "amount above the credential maximum of %v", int64(c.maxAmount))
+	}
+	return nil
+}
+
+// loadAuth reads REQUIRE_AUTH (default true).
+func loadAuth(c *config) error {
+	if s := os.Getenv("REQUIRE_AUTH"); s != "" {
+		require, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("REQUIRE_AUTH=%v: %w", s, err)
+		}
+		c.requireAuth = require
+	}
+	return nil
+}
+
+// credentialID returns the id of c, nil if c is nil.
+func (c *credential) credentialID() []byte {
+	if c == nil {
+		return nil
+	}
+	return c.id
+}
+
+type credentialKey struct{}
+
+// credentialFromContext returns the credential authenticating the request,
+// nil if none.
+func credentialFromContext(ctx context.Context) *credential {
+	c, _ := ctx.Value(credentialKey{}).(*credential)
+	return c
+}
+
+// parseAPIKey splits an API key, "<id>.<secret>" in hex, into its parts.
+func parseAPIKey(key string) (id, secret []byte, err error) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 {
+		return nil, nil, errors.New("malformed API key")
+	}
+	id, err = hex.DecodeString(parts[0])
+	if err != nil || len(id) != credentialIDLen {
+		return nil, nil, errors.New("malformed API key")
+	}
+	secret, err = hex.DecodeString(parts[1])
+	if err != nil || len(secret) != credentialSecretLen {
+		return nil, nil, errors.New("malformed API key")
+	}
+	return id, secret, nil
+}
+
+// apiKey returns the key sent in the x-api-key header or as an
+// "authorization: Bearer" token, "" if none.
+func apiKey(md metadata.MD) string {
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		return v[0]
+	}
+	for _, v := range md.Get("authorization") {
+		if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+			return strings.TrimSpace(v[7:])
+		}
+	}
+	return ""
+}
+
+// authenticate returns the credential of the API key in the metadata of ctx
+// if it is valid for method. It returns nil and no error when no key is sent
+// and authentication isn't required.
+func authenticate(ctx context.Context, method string) (*credential, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := apiKey(md)
+	if key == "" {
+		if currentConfig().requireAuth {
+			return nil, status.Error(codes.Unauthenticated, "API key required")
+		}
+		return nil, nil
+	}
+	id, secret, err := parseAPIKey(key)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	c, err := getCredential(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(secret)
+	if c == nil || subtle.ConstantTimeCompare(h[:], c.secretHash) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "API key not valid")
+	}
+	if c.revoked || (!c.expiry.IsZero() && time.Now().After(c.expiry)) {
+		return nil, status.Error(codes.Unauthenticated, "API key expired or revoked")
+	}
+	if !c.allows(method) {
+		return nil, status.Errorf(codes.PermissionDenied, "API key not allowed to call %v", method)
+	}
+	return c, nil
+}
+
+// authInterceptor authenticates each unary call and passes the credential on
+// in the context. Health checks need no credential.
+func authInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+		return handler(ctx, req)
+	}
+	c, err := authenticate(ctx, path.Base(info.FullMethod))
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		ctx = context.WithValue(ctx, credentialKey{}, c)
+	}
+	return handler(ctx, req)
+}
+
+// newCredential stores a new credential and returns its API key.
+func newCredential(ctx context.Context, name string, methods []string, maxAmount btcutil.Amount, expiry time.Time) (string, error) {
+	id := make([]byte, credentialIDLen)
+	secret := make([]byte, credentialSecretLen)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(secret)
+	err := saveCredential(ctx, &credential{
+		id:         id,
+		name:       name,
+		secretHash: h[:],
+		methods:    methods,
+		maxAmount:  maxAmount,
+		expiry:     expiry,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id) + "." + hex.EncodeToString(secret), nil
+}
+
+// issueCredentialCommand implements "swapper issue-credential": it stores a
+// new credential and prints its API key.
+func issueCredentialCommand(args []string) error {
+	fs := flag.NewFlagSet("issue-credential", flag.ContinueOnError)
+	name := fs.String("name", "", "client the credential is issued to")
+	methods := fs.String("methods", "", "comma separated RPC methods allowed, all if empty")
+	maxAmount := fs.Int64("max-amount", 0, "largest swap amount in satoshis, 0 for no cap")
+	ttl := fs.Duration("ttl", 0, "validity of the credential, 0 for no expiry")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+	var allowed []string
+	if *methods != "" {
+		for _, m := range strings.Split(*methods, ",") {
+			allowed = append(allowed, strings.TrimSpace(m))
+		}
+	}
+	var expiry time.Time
+	if *ttl > 0 {
+		expiry = time.Now().Add(*ttl)
+	}
+	key, err := newCredential(context.Background(), *name, allowed, btcutil.Amount(*maxAmount), expiry)
+	if err != nil {
+		return err
+	}
+	fmt.Println(key)
+	return nil
+}
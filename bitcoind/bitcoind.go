@@ -0,0 +1,383 @@
+// Package bitcoind implements the chain interfaces over the JSON-RPC and ZMQ
+// interfaces of a bitcoind node.
+package bitcoind
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"swapper/chain"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// Config describes how to reach bitcoind.
+type Config struct {
+	// Host is the host:port of the JSON-RPC interface.
+	Host string
+	User string
+	Pass string
+	// Wallet, if set, is a descriptor wallet into which tracked addresses
+	// are imported. UTXOs are then read with listunspent instead of the
+	// much slower scantxoutset.
+	Wallet string
+	// ZMQBlock and ZMQTx are the zmqpubrawblock and zmqpubrawtx endpoints.
+	// Both are needed for notifications.
+	ZMQBlock string
+	ZMQTx    string
+	Params   *chaincfg.Params
+}
+
+// Backend implements chain.Backend, chain.Notifier, chain.BlockHasher,
+// chain.MempoolReader and, with a wallet, chain.UnconfirmedUtxoReader.
+type Backend struct {
+	cfg    Config
+	client *rpcclient.Client
+
+	blocks   chan chain.Block
+	deposits chan chain.Deposit
+
+	mu        sync.Mutex
+	addresses map[string]struct{}
+}
+
+var (
+	_ chain.Backend               = (*Backend)(nil)
+	_ chain.Notifier              = (*Backend)(nil)
+	_ chain.BlockHasher           = (*Backend)(nil)
+	_ chain.MempoolReader         = (*Backend)(nil)
+	_ chain.UnconfirmedUtxoReader = (*Backend)(nil)
+)
+
+// New returns a backend talking to the bitcoind described by cfg.
+func New(cfg Config) (*Backend, error) {
+	host := cfg.Host
+	if cfg.Wallet != "" {
+		host += "/wallet/" + cfg.Wallet
+	}
+	client, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         host,
+		User:         cfg.User,
+		Pass:         cfg.Pass,
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("bitcoind: %w", err)
+	}
+	return &Backend{
+		cfg:       cfg,
+		client:    client,
+		blocks:    make(chan chain.Block, 16),
+		deposits:  make(chan chain.Deposit, 256),
+		addresses: make(map[string]struct{}),
+	}, nil
+}
+
+// call runs f, which cannot be interrupted, and returns early if ctx is done.
+func call(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- f()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Backend) rawRequest(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	rawParams := make([]json.RawMessage, 0, len(params))
+	for _, p := range params {
+		raw, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		rawParams = append(rawParams, raw)
+	}
+	var raw json.RawMessage
+	err := call(ctx, func() error {
+		var err error
+		raw, err = b.client.RawRequest(method, rawParams)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("bitcoind %v: %w", method, err)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("bitcoind %v: %w", method, err)
+	}
+	return nil
+}
+
+// RecommendedFee implements chain.Backend with estimatesmartfee.
+func (b *Backend) RecommendedFee(ctx context.Context) (uint64, error) {
+	var res *btcjson.EstimateSmartFeeResult
+	err := call(ctx, func() error {
+		var err error
+		res, err = b.client.EstimateSmartFee(1, &btcjson.EstimateModeConservative)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("bitcoind estimatesmartfee: %w", err)
+	}
+	if res.FeeRate == nil {
+		return 0, fmt.Errorf("bitcoind estimatesmartfee: no estimate: %v", res.Errors)
+	}
+	// BTC/kvB to sat/vB.
+	return uint64(math.Ceil(*res.FeeRate * btcutil.SatoshiPerBitcoin / 1000)), nil
+}
+
+type unspent struct {
+	Txid   string  `json:"txid"`
+	Vout   uint32  `json:"vout"`
+	Amount float64 `json:"amount"`
+	// Height is set by scantxoutset, Confirmations by listunspent.
+	Height        int32 `json:"height"`
+	Confirmations int32 `json:"confirmations"`
+}
+
+// GetUtxos implements chain.Backend with listunspent when a wallet is
+// configured, scantxoutset otherwise.
+func (b *Backend) GetUtxos(ctx context.Context, address btcutil.Address) ([]chain.Utxo, error) {
+	var unspents []unspent
+	var tip int32
+	if b.cfg.Wallet != "" {
+		height, err := b.CurrentHeight(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tip = int32(height)
+		err = b.rawRequest(ctx, &unspents, "listunspent", 1, 9999999,
+			[]string{address.EncodeAddress()}, true)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var res struct {
+			Unspents []unspent `json:"unspents"`
+		}
+		err := b.rawRequest(ctx, &res, "scantxoutset", "start",
+			[]string{"addr(" + address.EncodeAddress() + ")"})
+		if err != nil {
+			return nil, err
+		}
+		unspents = res.Unspents
+	}
+
+	var utxos []chain.Utxo
+	for _, u := range unspents {
+		txid, err := chainhash.NewHashFromStr(u.Txid)
+		if err != nil {
+			return nil, fmt.Errorf("bitcoind: txid %v: %w", u.Txid, err)
+		}
+		value, err := btcutil.NewAmount(u.Amount)
+		if err != nil {
+			return nil, err
+		}
+		height := u.Height
+		if b.cfg.Wallet != "" {
+			height = tip - u.Confirmations + 1
+		}
+		utxos = append(utxos, chain.Utxo{
+			Value:       value,
+			BlockHeight: height,
+			OutPoint:    *wire.NewOutPoint(txid, u.Vout),
+		})
+	}
+	return utxos, nil
+}
+
+// GetUnconfirmedUtxos implements chain.UnconfirmedUtxoReader with
+// listunspent. scantxoutset doesn't see the mempool, so it needs a wallet.
+func (b *Backend) GetUnconfirmedUtxos(ctx context.Context, address btcutil.Address) ([]chain.Utxo, error) {
+	if b.cfg.Wallet == "" {
+		return nil, chain.ErrMempoolUnsupported
+	}
+	var unspents []unspent
+	err := b.rawRequest(ctx, &unspents, "listunspent", 0, 0,
+		[]string{address.EncodeAddress()}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var utxos []chain.Utxo
+	for _, u := range unspents {
+		txid, err := chainhash.NewHashFromStr(u.Txid)
+		if err != nil {
+			return nil, fmt.Errorf("bitcoind: txid %v: %w", u.Txid, err)
+		}
+		value, err := btcutil.NewAmount(u.Amount)
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, chain.Utxo{Value: value, OutPoint: *wire.NewOutPoint(txid, u.Vout)})
+	}
+	return utxos, nil
+}
+
+// BroadcastTransaction implements chain.Backend with sendrawtransaction.
+func (b *Backend) BroadcastTransaction(ctx context.Context, tx *wire.MsgTx) (*chainhash.Hash, error) {
+	var txid *chainhash.Hash
+	err := call(ctx, func() error {
+		var err error
+		txid, err = b.client.SendRawTransaction(tx, false)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bitcoind sendrawtransaction: %w", err)
+	}
+	return txid, nil
+}
+
+// CurrentHeight implements chain.Backend with getblockcount.
+func (b *Backend) CurrentHeight(ctx context.Context) (uint32, error) {
+	var height int64
+	err := call(ctx, func() error {
+		var err error
+		height, err = b.client.GetBlockCount()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("bitcoind getblockcount: %w", err)
+	}
+	return uint32(height), nil
+}
+
+// BlockHash implements chain.BlockHasher with getblockhash.
+func (b *Backend) BlockHash(ctx context.Context, height int32) (*chainhash.Hash, error) {
+	var hash *chainhash.Hash
+	err := call(ctx, func() error {
+		var err error
+		hash, err = b.client.GetBlockHash(int64(height))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bitcoind getblockhash: %w", err)
+	}
+	return hash, nil
+}
+
+// GetMempoolTx implements chain.MempoolReader with getmempoolentry, falling
+// back to getrawtransaction to tell confirmed transactions apart.
+func (b *Backend) GetMempoolTx(ctx context.Context, txid chainhash.Hash) (*chain.MempoolTx, error) {
+	var entry struct {
+		VSize int64 `json:"vsize"`
+		Fees  struct {
+			Base float64 `json:"base"`
+		} `json:"fees"`
+		Replaceable bool     `json:"bip125-replaceable"`
+		Depends     []string `json:"depends"`
+	}
+	err := b.rawRequest(ctx, &entry, "getmempoolentry", txid.String())
+	if err != nil {
+		var tx struct {
+			Confirmations int64 `json:"confirmations"`
+		}
+		if b.rawRequest(ctx, &tx, "getrawtransaction", txid.String(), true) == nil && tx.Confirmations > 0 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	fee, err := btcutil.NewAmount(entry.Fees.Base)
+	if err != nil {
+		return nil, err
+	}
+	return &chain.MempoolTx{
+		Fee:   fee,
+		VSize: entry.VSize,
+		// bip125-replaceable includes the replaceability inherited from
+		// unconfirmed parents.
+		Replaceable:        entry.Replaceable,
+		UnconfirmedParents: len(entry.Depends),
+	}, nil
+}
+
+// TrackAddress implements chain.AddressTracker. With a wallet configured the
+// address is also imported as a watch-only descriptor, rescanning the blocks
+// since created.
+func (b *Backend) TrackAddress(address string, created time.Time) error {
+	b.mu.Lock()
+	_, ok := b.addresses[address]
+	b.addresses[address] = struct{}{}
+	b.mu.Unlock()
+	if ok || b.cfg.Wallet == "" {
+		return nil
+	}
+	if err := b.importAddress(context.Background(), address, created); err != nil {
+		// Import again on the next call.
+		b.mu.Lock()
+		delete(b.addresses, address)
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// UntrackAddress implements chain.AddressTracker. The descriptor, if imported,
+// stays in the wallet.
+func (b *Backend) UntrackAddress(address string) error {
+	b.mu.Lock()
+	delete(b.addresses, address)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *Backend) tracked(address string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.addresses[address]
+	return ok
+}
+
+// importAddress imports addr(address) into the wallet with importdescriptors.
+// bitcoind rescans the blocks since created, less its two hours margin for
+// block timestamps, so the deposits made before the import are found.
+func (b *Backend) importAddress(ctx context.Context, address string, created time.Time) error {
+	var info struct {
+		Descriptor string `json:"descriptor"`
+	}
+	err := b.rawRequest(ctx, &info, "getdescriptorinfo", "addr("+address+")")
+	if err != nil {
+		return err
+	}
+
+	var res []struct {
+		Success bool `json:"success"`
+		Error   *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	err = b.rawRequest(ctx, &res, "importdescriptors", []map[string]interface{}{{
+		"desc":      info.Descriptor,
+		"timestamp": created.Unix(),
+		"label":     "swap",
+	}})
+	if err != nil {
+		return err
+	}
+	if len(res) != 1 || !res[0].Success {
+		if len(res) == 1 && res[0].Error != nil {
+			return fmt.Errorf("bitcoind importdescriptors %v: %v", address, res[0].Error.Message)
+		}
+		return errors.New("bitcoind importdescriptors: failed")
+	}
+	return nil
+}
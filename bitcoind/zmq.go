@@ -0,0 +1,161 @@
+package bitcoind
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"swapper/chain"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightninglabs/gozmq"
+)
+
+const (
+	zmqReadTimeout = 5 * time.Second
+)
+
+// Blocks implements chain.Notifier.
+func (b *Backend) Blocks() <-chan chain.Block {
+	return b.blocks
+}
+
+// Deposits implements chain.Notifier.
+func (b *Backend) Deposits() <-chan chain.Deposit {
+	return b.deposits
+}
+
+// Run implements chain.Notifier by subscribing to the zmqpubrawblock and
+// zmqpubrawtx endpoints of bitcoind. gozmq reconnects by itself.
+func (b *Backend) Run(ctx context.Context) error {
+	if b.cfg.ZMQBlock == "" || b.cfg.ZMQTx == "" {
+		return errors.New("bitcoind: zmq endpoints not configured")
+	}
+
+	blockConn, err := gozmq.Subscribe(b.cfg.ZMQBlock, []string{"rawblock"}, zmqReadTimeout)
+	if err != nil {
+		return fmt.Errorf("bitcoind zmq %v: %w", b.cfg.ZMQBlock, err)
+	}
+	defer blockConn.Close()
+	txConn, err := gozmq.Subscribe(b.cfg.ZMQTx, []string{"rawtx"}, zmqReadTimeout)
+	if err != nil {
+		return fmt.Errorf("bitcoind zmq %v: %w", b.cfg.ZMQTx, err)
+	}
+	defer txConn.Close()
+
+	errc := make(chan error, 2)
+	go func() {
+		errc <- b.receive(ctx, blockConn, b.handleBlock)
+	}()
+	go func() {
+		errc <- b.receive(ctx, txConn, b.handleTx)
+	}()
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return err
+}
+
+// receive passes the body of every message received on conn to handle.
+func (b *Backend) receive(ctx context.Context, conn *gozmq.Conn, handle func(context.Context, []byte) error) error {
+	bufs := make([][]byte, 0, 3)
+	for ctx.Err() == nil {
+		msg, err := conn.Receive(bufs)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+		// topic, body, sequence number.
+		if len(msg) < 2 {
+			continue
+		}
+		if err := handle(ctx, msg[1]); err != nil {
+			slog.Warn("bitcoind zmq message failed", "topic", string(msg[0]), "error", err)
+		}
+	}
+	return ctx.Err()
+}
+
+func (b *Backend) handleBlock(ctx context.Context, raw []byte) error {
+	var block wire.MsgBlock
+	if err := block.Deserialize(bytes.NewReader(raw)); err != nil {
+		return err
+	}
+	hash := block.BlockHash()
+
+	// The raw block doesn't carry its height.
+	var height int32
+	err := call(ctx, func() error {
+		header, err := b.client.GetBlockHeaderVerbose(&hash)
+		if err != nil {
+			return err
+		}
+		height = header.Height
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("getblockheader %v: %w", hash, err)
+	}
+
+	for _, tx := range block.Transactions {
+		err := b.deliverDeposits(ctx, tx, chain.Deposit{
+			Confirmed:   true,
+			BlockHeight: height,
+			BlockHash:   hash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	select {
+	case b.blocks <- chain.Block{Hash: hash, PrevHash: block.Header.PrevBlock, Height: height}:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (b *Backend) handleTx(ctx context.Context, raw []byte) error {
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return err
+	}
+	return b.deliverDeposits(ctx, &tx, chain.Deposit{})
+}
+
+// deliverDeposits sends a copy of template for each output of tx paying to a
+// tracked address.
+func (b *Backend) deliverDeposits(ctx context.Context, tx *wire.MsgTx, template chain.Deposit) error {
+	txHash := tx.TxHash()
+	for vout, out := range tx.TxOut {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(out.PkScript, b.cfg.Params)
+		if err != nil || len(addrs) != 1 {
+			continue
+		}
+		address := addrs[0].EncodeAddress()
+		if !b.tracked(address) {
+			continue
+		}
+		d := template
+		d.Address = address
+		d.OutPoint = *wire.NewOutPoint(&txHash, uint32(vout))
+		d.Value = btcutil.Amount(out.Value)
+		select {
+		case b.deposits <- d:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
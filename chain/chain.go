@@ -0,0 +1,37 @@
+// Package chain defines the interface the swapper uses to read and write the
+// bitcoin chain, and a composite backend spreading it over several providers.
+package chain
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// Utxo is an unspent output paying to a swap address.
+type Utxo struct {
+	Value       btcutil.Amount
+	BlockHeight int32
+	wire.OutPoint
+}
+
+// Backend is a source of chain data.
+type Backend interface {
+	// RecommendedFee returns the fee rate, in sat/vbyte, recommended by
+	// the backend for the redeem transactions.
+	RecommendedFee(ctx context.Context) (uint64, error)
+	// GetUtxos returns the confirmed utxos of address.
+	GetUtxos(ctx context.Context, address btcutil.Address) ([]Utxo, error)
+	// BroadcastTransaction broadcasts tx and returns its txid.
+	BroadcastTransaction(ctx context.Context, tx *wire.MsgTx) (*chainhash.Hash, error)
+	// CurrentHeight returns the height of the chain tip.
+	CurrentHeight(ctx context.Context) (uint32, error)
+}
+
+// BlockHasher is implemented by backends able to return the hash of the
+// block at a height of their best chain, used to detect reorganizations.
+type BlockHasher interface {
+	BlockHash(ctx context.Context, height int32) (*chainhash.Hash, error)
+}
@@ -0,0 +1,94 @@
+package chain
+
+import (
+	"context"
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+// ErrMempoolUnsupported is returned by Multi.GetMempoolTx when no provider
+// reads the mempool.
+var ErrMempoolUnsupported = errors.New("chain: no provider reads the mempool")
+
+// MempoolTx describes an unconfirmed transaction, as needed to judge the
+// risk of accepting it before it confirms.
+type MempoolTx struct {
+	Fee   btcutil.Amount
+	VSize int64
+	// Replaceable reports whether the transaction signals BIP 125
+	// replaceability itself.
+	Replaceable bool
+	// UnconfirmedParents is the number of unconfirmed transactions it
+	// spends from. Their own replaceability is inherited.
+	UnconfirmedParents int
+}
+
+// FeeRate returns the fee rate of the transaction in sat/vbyte.
+func (t *MempoolTx) FeeRate() float64 {
+	if t.VSize == 0 {
+		return 0
+	}
+	return float64(t.Fee) / float64(t.VSize)
+}
+
+// MempoolReader is implemented by backends able to describe unconfirmed
+// transactions.
+type MempoolReader interface {
+	// GetMempoolTx returns the mempool transaction txid, or nil if it is
+	// confirmed.
+	GetMempoolTx(ctx context.Context, txid chainhash.Hash) (*MempoolTx, error)
+}
+
+// UnconfirmedUtxoReader is implemented by backends able to list the outputs
+// paying to an address seen only in the mempool. Those which can't in their
+// configuration return ErrMempoolUnsupported.
+type UnconfirmedUtxoReader interface {
+	// GetUnconfirmedUtxos returns the unconfirmed utxos of address.
+	GetUnconfirmedUtxos(ctx context.Context, address btcutil.Address) ([]Utxo, error)
+}
+
+// GetMempoolTx implements MempoolReader by failover over the providers that
+// implement it.
+func (m *Multi) GetMempoolTx(ctx context.Context, txid chainhash.Hash) (*MempoolTx, error) {
+	var tx *MempoolTx
+	supported := false
+	_, err := m.failover(ctx, func(_ int, p Backend) error {
+		reader, ok := p.(MempoolReader)
+		if !ok {
+			return ErrMempoolUnsupported
+		}
+		supported = true
+		var err error
+		tx, err = reader.GetMempoolTx(ctx, txid)
+		return err
+	})
+	if !supported {
+		return nil, ErrMempoolUnsupported
+	}
+	return tx, err
+}
+
+// GetUnconfirmedUtxos implements UnconfirmedUtxoReader by failover over the
+// providers that implement it.
+func (m *Multi) GetUnconfirmedUtxos(ctx context.Context, address btcutil.Address) ([]Utxo, error) {
+	var utxos []Utxo
+	supported := false
+	_, err := m.failover(ctx, func(_ int, p Backend) error {
+		reader, ok := p.(UnconfirmedUtxoReader)
+		if !ok {
+			return ErrMempoolUnsupported
+		}
+		var err error
+		utxos, err = reader.GetUnconfirmedUtxos(ctx, address)
+		if !errors.Is(err, ErrMempoolUnsupported) {
+			supported = true
+		}
+		return err
+	})
+	if !supported {
+		return nil, ErrMempoolUnsupported
+	}
+	return utxos, err
+}
@@ -0,0 +1,236 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// ErrNoQuorum is returned when not enough providers agree on a result.
+var ErrNoQuorum = errors.New("chain: providers disagree")
+
+// Multi is a Backend over several providers. Fee estimation and broadcast go
+// to the first provider that answers, in order. UTXO sets and the tip height,
+// which the swapper acts upon, must be reported identically by quorum
+// providers.
+type Multi struct {
+	providers []Backend
+	quorum    int
+}
+
+// NewMulti returns a Backend over providers requiring quorum of them to agree
+// on UTXO sets and tip height. A quorum of 1 means plain failover.
+func NewMulti(quorum int, providers ...Backend) (*Multi, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("chain: no provider")
+	}
+	if quorum < 1 || quorum > len(providers) {
+		return nil, fmt.Errorf("chain: quorum %v with %v providers", quorum, len(providers))
+	}
+	return &Multi{providers: providers, quorum: quorum}, nil
+}
+
+// failover calls f on each provider in turn until one succeeds and returns
+// the index of that provider.
+func (m *Multi) failover(ctx context.Context, f func(int, Backend) error) (int, error) {
+	var errs []string
+	for i, p := range m.providers {
+		err := f(i, p)
+		if err == nil {
+			return i, nil
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		errs = append(errs, fmt.Sprintf("provider %v: %v", i, err))
+	}
+	return 0, fmt.Errorf("chain: all providers failed: %v", strings.Join(errs, "; "))
+}
+
+// agree calls f on every provider concurrently and returns the index of a
+// provider whose result, as identified by key, is shared by at least quorum
+// providers. With a quorum of 1 it behaves like failover.
+func (m *Multi) agree(ctx context.Context, f func(int, Backend) (string, error)) (int, error) {
+	if m.quorum == 1 {
+		return m.failover(ctx, func(i int, p Backend) error {
+			_, err := f(i, p)
+			return err
+		})
+	}
+
+	keys := make([]string, len(m.providers))
+	errs := make([]error, len(m.providers))
+	var wg sync.WaitGroup
+	for i, p := range m.providers {
+		wg.Add(1)
+		go func(i int, p Backend) {
+			defer wg.Done()
+			keys[i], errs[i] = f(i, p)
+		}(i, p)
+	}
+	wg.Wait()
+
+	votes := make(map[string]int)
+	for i, key := range keys {
+		if errs[i] != nil {
+			continue
+		}
+		votes[key]++
+		if votes[key] >= m.quorum {
+			return i, nil
+		}
+	}
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	return 0, fmt.Errorf("%w: %v of %v providers answered, quorum is %v",
+		ErrNoQuorum, len(m.providers)-countErrors(errs), len(m.providers), m.quorum)
+}
+
+func countErrors(errs []error) int {
+	n := 0
+	for _, err := range errs {
+		if err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *Multi) RecommendedFee(ctx context.Context) (uint64, error) {
+	var fee uint64
+	_, err := m.failover(ctx, func(_ int, p Backend) error {
+		var err error
+		fee, err = p.RecommendedFee(ctx)
+		return err
+	})
+	return fee, err
+}
+
+func (m *Multi) GetUtxos(ctx context.Context, address btcutil.Address) ([]Utxo, error) {
+	results := make([][]Utxo, len(m.providers))
+	i, err := m.agree(ctx, func(i int, p Backend) (string, error) {
+		utxos, err := p.GetUtxos(ctx, address)
+		if err != nil {
+			return "", err
+		}
+		results[i] = utxos
+		return utxosKey(utxos), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results[i], nil
+}
+
+// utxosKey identifies a UTXO set independently of its order.
+func utxosKey(utxos []Utxo) string {
+	keys := make([]string, 0, len(utxos))
+	for _, u := range utxos {
+		keys = append(keys, fmt.Sprintf("%v:%v:%v", u.OutPoint, int64(u.Value), u.BlockHeight))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func (m *Multi) BroadcastTransaction(ctx context.Context, tx *wire.MsgTx) (*chainhash.Hash, error) {
+	var txid *chainhash.Hash
+	_, err := m.failover(ctx, func(_ int, p Backend) error {
+		var err error
+		txid, err = p.BroadcastTransaction(ctx, tx)
+		return err
+	})
+	return txid, err
+}
+
+func (m *Multi) CurrentHeight(ctx context.Context) (uint32, error) {
+	heights := make([]uint32, len(m.providers))
+	i, err := m.agree(ctx, func(i int, p Backend) (string, error) {
+		height, err := p.CurrentHeight(ctx)
+		if err != nil {
+			return "", err
+		}
+		heights[i] = height
+		return fmt.Sprint(height), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return heights[i], nil
+}
+
+// HashesBlocks reports whether at least quorum providers implement
+// BlockHasher, without which BlockHash always fails.
+func (m *Multi) HashesBlocks() bool {
+	n := 0
+	for _, p := range m.providers {
+		if _, ok := p.(BlockHasher); ok {
+			n++
+		}
+	}
+	return n >= m.quorum
+}
+
+// BlockHash implements BlockHasher. quorum providers implementing it must
+// agree on the hash.
+func (m *Multi) BlockHash(ctx context.Context, height int32) (*chainhash.Hash, error) {
+	hashes := make([]*chainhash.Hash, len(m.providers))
+	i, err := m.agree(ctx, func(i int, p Backend) (string, error) {
+		hasher, ok := p.(BlockHasher)
+		if !ok {
+			return "", errors.New("chain: provider doesn't return block hashes")
+		}
+		hash, err := hasher.BlockHash(ctx, height)
+		if err != nil {
+			return "", err
+		}
+		hashes[i] = hash
+		return hash.String(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes[i], nil
+}
+
+// TrackAddress implements AddressTracker on every provider implementing it,
+// so that they can all answer GetUtxos for address.
+func (m *Multi) TrackAddress(address string, created time.Time) error {
+	return m.trackers(func(t AddressTracker) error {
+		return t.TrackAddress(address, created)
+	})
+}
+
+// UntrackAddress implements AddressTracker on every provider implementing
+// it.
+func (m *Multi) UntrackAddress(address string) error {
+	return m.trackers(func(t AddressTracker) error {
+		return t.UntrackAddress(address)
+	})
+}
+
+// trackers calls f on every provider implementing AddressTracker.
+func (m *Multi) trackers(f func(AddressTracker) error) error {
+	var errs []string
+	for i, p := range m.providers {
+		t, ok := p.(AddressTracker)
+		if !ok {
+			continue
+		}
+		if err := f(t); err != nil {
+			errs = append(errs, fmt.Sprintf("provider %v: %v", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("chain: %v", strings.Join(errs, "; "))
+	}
+	return nil
+}
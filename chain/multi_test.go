@@ -0,0 +1,226 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+var errProvider = errors.New("provider down")
+
+// fakeBackend answers with fixed values, or err.
+type fakeBackend struct {
+	height uint32
+	utxos  []Utxo
+	fee    uint64
+	err    error
+	calls  int
+}
+
+func (f *fakeBackend) RecommendedFee(ctx context.Context) (uint64, error) {
+	f.calls++
+	return f.fee, f.err
+}
+
+func (f *fakeBackend) GetUtxos(ctx context.Context, address btcutil.Address) ([]Utxo, error) {
+	f.calls++
+	return f.utxos, f.err
+}
+
+func (f *fakeBackend) BroadcastTransaction(ctx context.Context, tx *wire.MsgTx) (*chainhash.Hash, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	h := tx.TxHash()
+	return &h, nil
+}
+
+func (f *fakeBackend) CurrentHeight(ctx context.Context) (uint32, error) {
+	f.calls++
+	return f.height, f.err
+}
+
+func TestNewMulti(t *testing.T) {
+	p := &fakeBackend{}
+	for _, quorum := range []int{0, 3} {
+		if _, err := NewMulti(quorum, p, p); err == nil {
+			t.Errorf("NewMulti(%v) with 2 providers accepted", quorum)
+		}
+	}
+	if _, err := NewMulti(1); err == nil {
+		t.Error("NewMulti() without provider accepted")
+	}
+}
+
+func TestMultiCurrentHeight(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		quorum    int
+		providers []*fakeBackend
+		want      uint32
+		// wantErr is nil, ErrNoQuorum, or errProvider for any other error.
+		wantErr error
+	}{
+		{
+			name:      "failover to the second provider",
+			quorum:    1,
+			providers: []*fakeBackend{{err: errProvider}, {height: 100}},
+			want:      100,
+		},
+		{
+			name:      "all providers failing",
+			quorum:    1,
+			providers: []*fakeBackend{{err: errProvider}, {err: errProvider}},
+			wantErr:   errProvider,
+		},
+		{
+			name:      "agreement",
+			quorum:    2,
+			providers: []*fakeBackend{{height: 100}, {height: 100}, {height: 100}},
+			want:      100,
+		},
+		{
+			name:      "majority despite a disagreeing provider",
+			quorum:    2,
+			providers: []*fakeBackend{{height: 99}, {height: 100}, {height: 100}},
+			want:      100,
+		},
+		{
+			name:      "majority despite a failing primary",
+			quorum:    2,
+			providers: []*fakeBackend{{err: errProvider}, {height: 100}, {height: 100}},
+			want:      100,
+		},
+		{
+			name:      "disagreement",
+			quorum:    2,
+			providers: []*fakeBackend{{height: 99}, {height: 100}, {height: 101}},
+			wantErr:   ErrNoQuorum,
+		},
+		{
+			name:      "quorum not reached",
+			quorum:    2,
+			providers: []*fakeBackend{{height: 100}, {err: errProvider}, {err: errProvider}},
+			wantErr:   ErrNoQuorum,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var providers []Backend
+			for _, p := range tt.providers {
+				providers = append(providers, p)
+			}
+			m, err := NewMulti(tt.quorum, providers...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			height, err := m.CurrentHeight(context.Background())
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("CurrentHeight() error: %v", err)
+			case tt.wantErr == ErrNoQuorum && !errors.Is(err, ErrNoQuorum):
+				t.Fatalf("CurrentHeight() error = %v, want ErrNoQuorum", err)
+			case tt.wantErr != nil && err == nil:
+				t.Fatalf("CurrentHeight() = %v, want an error", height)
+			}
+			if err == nil && height != tt.want {
+				t.Errorf("CurrentHeight() = %v, want %v", height, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiGetUtxos(t *testing.T) {
+	address, err := btcutil.NewAddressWitnessScriptHash(make([]byte, 32), &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u1 := Utxo{Value: 1000, BlockHeight: 100, OutPoint: wire.OutPoint{Index: 1}}
+	u2 := Utxo{Value: 2000, BlockHeight: 101, OutPoint: wire.OutPoint{Index: 2}}
+	unconfirmed := u2
+	unconfirmed.BlockHeight = 0
+
+	for _, tt := range []struct {
+		name      string
+		providers []*fakeBackend
+		want      int
+		wantErr   bool
+	}{
+		{
+			name:      "same set in another order",
+			providers: []*fakeBackend{{utxos: []Utxo{u1, u2}}, {utxos: []Utxo{u2, u1}}},
+			want:      2,
+		},
+		{
+			name:      "missing utxo",
+			providers: []*fakeBackend{{utxos: []Utxo{u1, u2}}, {utxos: []Utxo{u1}}},
+			wantErr:   true,
+		},
+		{
+			name:      "different confirmation",
+			providers: []*fakeBackend{{utxos: []Utxo{u1, u2}}, {utxos: []Utxo{u1, unconfirmed}}},
+			wantErr:   true,
+		},
+		{
+			name:      "failing provider",
+			providers: []*fakeBackend{{utxos: []Utxo{u1}}, {err: errProvider}},
+			wantErr:   true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMulti(2, tt.providers[0], tt.providers[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			utxos, err := m.GetUtxos(context.Background(), address)
+			if tt.wantErr {
+				if !errors.Is(err, ErrNoQuorum) {
+					t.Fatalf("GetUtxos() error = %v, want ErrNoQuorum", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetUtxos() error: %v", err)
+			}
+			if len(utxos) != tt.want {
+				t.Errorf("GetUtxos() = %v, want %v utxos", utxos, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiFailover(t *testing.T) {
+	primary := &fakeBackend{err: errProvider}
+	secondary := &fakeBackend{fee: 12}
+	unused := &fakeBackend{fee: 50}
+	// Fee estimation and broadcast never need a quorum.
+	m, err := NewMulti(3, primary, secondary, unused)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fee, err := m.RecommendedFee(context.Background())
+	if err != nil {
+		t.Fatalf("RecommendedFee() error: %v", err)
+	}
+	if fee != 12 {
+		t.Errorf("RecommendedFee() = %v, want 12 from the second provider", fee)
+	}
+	if _, err := m.BroadcastTransaction(context.Background(), wire.NewMsgTx(2)); err != nil {
+		t.Fatalf("BroadcastTransaction() error: %v", err)
+	}
+	if unused.calls != 0 {
+		t.Errorf("third provider called %v times after the second answered", unused.calls)
+	}
+
+	secondary.err = errProvider
+	unused.err = errProvider
+	if _, err := m.RecommendedFee(context.Background()); err == nil {
+		t.Error("RecommendedFee() succeeded with all providers failing")
+	}
+}
@@ -0,0 +1,52 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// Block is a block connected to the chain tip.
+type Block struct {
+	Hash     chainhash.Hash
+	PrevHash chainhash.Hash
+	Height   int32
+}
+
+// Deposit is an output paying to a tracked address, seen either in the
+// mempool or in a block.
+type Deposit struct {
+	Address string
+	wire.OutPoint
+	Value       btcutil.Amount
+	Confirmed   bool
+	BlockHeight int32
+	BlockHash   chainhash.Hash
+}
+
+// AddressTracker is implemented by the providers which only know about the
+// addresses they were asked to follow.
+type AddressTracker interface {
+	// TrackAddress adds address to the followed addresses. created is when
+	// the address was created: the outputs paying to it since then must be
+	// found.
+	TrackAddress(address string, created time.Time) error
+	// UntrackAddress stops following address.
+	UntrackAddress(address string) error
+}
+
+// Notifier delivers new blocks and deposits to tracked addresses.
+type Notifier interface {
+	AddressTracker
+	// Run delivers events until ctx is done.
+	Run(ctx context.Context) error
+	// Blocks delivers every new block.
+	Blocks() <-chan Block
+	// Deposits delivers the outputs paying to tracked addresses, once when
+	// seen in the mempool and again when confirmed. Deposits may be
+	// delivered more than once, so consumers must be idempotent.
+	Deposits() <-chan Deposit
+}
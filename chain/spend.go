@@ -0,0 +1,63 @@
+package chain
+
+import (
+	"context"
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrSpendUnsupported is returned by Multi.GetSpend when no provider can find
+// spends.
+var ErrSpendUnsupported = errors.New("chain: no provider finds spends")
+
+// Spend is the input of a transaction spending an output.
+type Spend struct {
+	Tx          *wire.MsgTx
+	InputIndex  uint32
+	Confirmed   bool
+	BlockHeight int32
+	// BlockHash is the hash of the block holding the confirmed spend, zero
+	// if the provider doesn't report it.
+	BlockHash chainhash.Hash
+}
+
+// Witness returns the witness of the spending input.
+func (s *Spend) Witness() wire.TxWitness {
+	return s.Tx.TxIn[s.InputIndex].Witness
+}
+
+// Txid returns the txid of the spending transaction.
+func (s *Spend) Txid() chainhash.Hash {
+	return s.Tx.TxHash()
+}
+
+// SpendFinder is implemented by backends able to find the transaction
+// spending an output.
+type SpendFinder interface {
+	// GetSpend returns the spend of op, seen either in the mempool or in a
+	// block, or nil if op is unspent.
+	GetSpend(ctx context.Context, op wire.OutPoint) (*Spend, error)
+}
+
+// GetSpend implements SpendFinder by failover over the providers that
+// implement it.
+func (m *Multi) GetSpend(ctx context.Context, op wire.OutPoint) (*Spend, error) {
+	var spend *Spend
+	supported := false
+	_, err := m.failover(ctx, func(_ int, p Backend) error {
+		finder, ok := p.(SpendFinder)
+		if !ok {
+			return ErrSpendUnsupported
+		}
+		supported = true
+		var err error
+		spend, err = finder.GetSpend(ctx, op)
+		return err
+	})
+	if !supported {
+		return nil, ErrSpendUnsupported
+	}
+	return spend, err
+}
@@ -0,0 +1,332 @@
+// Command swapperctl talks to the AdminSwapper service of a swapper, served
+// on ADMIN_LISTEN_ADDRESS, to inspect swaps and act on them.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"swapper/adminrpc"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const usage = `usage: swapperctl [flags] <command> [command flags]
+
+commands:
+  list      list or search the swaps, newest first
+  show      show a swap and its deposits
+  setstate  move a swap to another state
+  redeem    redeem a swap with its preimage
+  refund    build the refund transaction of a swap for the payer to sign
+  bumpfee   replace the redeem transaction of a swap with a higher fee
+  export    export the swaps as json or csv
+  reload    reload the swapper settings
+
+flags:
+`
+
+var (
+	addr       = flag.String("addr", "localhost:8081", "admin address of the swapper")
+	serverCA   = flag.String("server-ca", "", "PEM file of the CA, or certificate, of the swapper")
+	clientCert = flag.String("cert", "", "PEM file of the operator certificate")
+	clientKey  = flag.String("key", "", "PEM file of the operator key")
+	timeout    = flag.Duration("timeout", 30*time.Second, "timeout of the call")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "swapperctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(command string, args []string) error {
+	commands := map[string]func(context.Context, adminrpc.AdminSwapperClient, []string) error{
+		"list":     list,
+		"show":     show,
+		"setstate": setState,
+		"redeem":   redeem,
+		"refund":   refund,
+		"bumpfee":  bumpFee,
+		"export":   export,
+		"reload":   reload,
+	}
+	cmd, ok := commands[command]
+	if !ok {
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	tlsConfig, err := clientTLSConfig()
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.Dial(*addr, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	return cmd(ctx, adminrpc.NewAdminSwapperClient(conn), args)
+}
+
+// clientTLSConfig presents the operator certificate and trusts the CA of the
+// swapper, or the system roots if none is given.
+func clientTLSConfig() (*tls.Config, error) {
+	if *clientCert == "" || *clientKey == "" {
+		return nil, errors.New("-cert and -key are required")
+	}
+	cert, err := tls.LoadX509KeyPair(*clientCert, *clientKey)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if *serverCA != "" {
+		pem, err := os.ReadFile(*serverCA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%v: no certificate", *serverCA)
+		}
+	}
+	return config, nil
+}
+
+// printJSON prints m in JSON, bytes fields in base64.
+func printJSON(m proto.Message) error {
+	b, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// hashFlag decodes the hex -hash flag.
+func hashFlag(s string) ([]byte, error) {
+	hash, err := hex.DecodeString(s)
+	if err != nil || len(hash) != 32 {
+		return nil, errors.New("-hash must be 32 bytes in hex")
+	}
+	return hash, nil
+}
+
+// filterFlags adds the flags selecting swaps to fs.
+func filterFlags(fs *flag.FlagSet) func() (*adminrpc.ListSwapsRequest, error) {
+	network := fs.String("network", "", "network, all if empty")
+	state := fs.String("state", "", "state (created, funded, claimed, claimed by third party, refunded, expired), all if empty")
+	search := fs.String("search", "", "swap address, refund address or beginning of the hash in hex")
+	after := fs.String("after", "", "only the swaps created at or after this RFC 3339 time")
+	before := fs.String("before", "", "only the swaps created before this RFC 3339 time")
+	return func() (*adminrpc.ListSwapsRequest, error) {
+		req := &adminrpc.ListSwapsRequest{Network: *network, State: *state, Search: *search}
+		for _, b := range []struct {
+			name  string
+			value string
+			unix  *int64
+		}{
+			{"-after", *after, &req.CreatedAfter},
+			{"-before", *before, &req.CreatedBefore},
+		} {
+			if b.value == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, b.value)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", b.name, err)
+			}
+			*b.unix = t.Unix()
+		}
+		return req, nil
+	}
+}
+
+func list(ctx context.Context, c adminrpc.AdminSwapperClient, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	filter := filterFlags(fs)
+	limit := fs.Int("limit", 50, "number of swaps, 0 for all")
+	offset := fs.Int("offset", 0, "number of swaps skipped")
+	fs.Parse(args)
+	req, err := filter()
+	if err != nil {
+		return err
+	}
+	req.Limit = int32(*limit)
+	req.Offset = int32(*offset)
+
+	resp, err := c.ListSwaps(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, s := range resp.Swaps {
+		fmt.Printf("%v %x %-8v %-22v %10v %v\n", time.Unix(s.Created, 0).UTC().Format(time.RFC3339),
+			s.Hash, s.Network, s.State, s.Amount, s.Address)
+	}
+	return nil
+}
+
+func show(ctx context.Context, c adminrpc.AdminSwapperClient, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	network := fs.String("network", "", "network, the default one if empty")
+	hashHex := fs.String("hash", "", "payment hash in hex")
+	fs.Parse(args)
+	hash, err := hashFlag(*hashHex)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.GetSwap(ctx, &adminrpc.GetSwapRequest{Network: *network, Hash: hash})
+	if err != nil {
+		return err
+	}
+	return printJSON(resp)
+}
+
+func redeem(ctx context.Context, c adminrpc.AdminSwapperClient, args []string) error {
+	fs := flag.NewFlagSet("redeem", flag.ExitOnError)
+	network := fs.String("network", "", "network, the default one if empty")
+	preimageHex := fs.String("preimage", "", "payment preimage in hex")
+	address := fs.String("address", "", "address receiving the deposits")
+	feeRate := fs.Int64("fee-rate", 0, "fee rate in sat/vbyte, the recommended one if 0")
+	fs.Parse(args)
+	preimage, err := hex.DecodeString(*preimageHex)
+	if err != nil || len(preimage) != 32 {
+		return errors.New("-preimage must be 32 bytes in hex")
+	}
+	if *address == "" {
+		return errors.New("-address is required")
+	}
+
+	resp, err := c.Redeem(ctx, &adminrpc.RedeemRequest{
+		Network:  *network,
+		Preimage: preimage,
+		Address:  *address,
+		FeeRate:  *feeRate,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(resp.Txid)
+	return nil
+}
+
+func refund(ctx context.Context, c adminrpc.AdminSwapperClient, args []string) error {
+	fs := flag.NewFlagSet("refund", flag.ExitOnError)
+	network := fs.String("network", "", "network, the default one if empty")
+	hashHex := fs.String("hash", "", "payment hash in hex")
+	address := fs.String("address", "", "address receiving the deposits, the refund address of the swap if empty")
+	feeRate := fs.Int64("fee-rate", 0, "fee rate in sat/vbyte, the recommended one if 0")
+	fs.Parse(args)
+	hash, err := hashFlag(*hashHex)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Refund(ctx, &adminrpc.RefundRequest{
+		Network: *network,
+		Hash:    hash,
+		Address: *address,
+		FeeRate: *feeRate,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(resp.Tx))
+	return nil
+}
+
+func bumpFee(ctx context.Context, c adminrpc.AdminSwapperClient, args []string) error {
+	fs := flag.NewFlagSet("bumpfee", flag.ExitOnError)
+	network := fs.String("network", "", "network, the default one if empty")
+	hashHex := fs.String("hash", "", "payment hash in hex")
+	feeRate := fs.Int64("fee-rate", 0, "new fee rate in sat/vbyte")
+	fs.Parse(args)
+	hash, err := hashFlag(*hashHex)
+	if err != nil {
+		return err
+	}
+	if *feeRate <= 0 {
+		return errors.New("-fee-rate is required")
+	}
+
+	resp, err := c.BumpFee(ctx, &adminrpc.BumpFeeRequest{Network: *network, Hash: hash, FeeRate: *feeRate})
+	if err != nil {
+		return err
+	}
+	fmt.Println(resp.Txid)
+	return nil
+}
+
+func export(ctx context.Context, c adminrpc.AdminSwapperClient, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	filter := filterFlags(fs)
+	format := fs.String("format", "json", "json or csv")
+	fs.Parse(args)
+	req, err := filter()
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.ExportSwaps(ctx, &adminrpc.ExportSwapsRequest{Filter: req, Format: *format})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(resp.Data)
+	return err
+}
+
+func setState(ctx context.Context, c adminrpc.AdminSwapperClient, args []string) error {
+	fs := flag.NewFlagSet("setstate", flag.ExitOnError)
+	network := fs.String("network", "", "network, the default one if empty")
+	hashHex := fs.String("hash", "", "payment hash in hex")
+	from := fs.String("from", "", "current state of the swap")
+	to := fs.String("to", "", "new state of the swap, a final one")
+	reason := fs.String("reason", "", "why the state is changed")
+	fs.Parse(args)
+	hash, err := hashFlag(*hashHex)
+	if err != nil {
+		return err
+	}
+	if *from == "" || *to == "" || *reason == "" {
+		return errors.New("-from, -to and -reason are required")
+	}
+
+	_, err = c.SetSwapState(ctx, &adminrpc.SetSwapStateRequest{
+		Network: *network,
+		Hash:    hash,
+		From:    *from,
+		To:      *to,
+		Reason:  *reason,
+	})
+	return err
+}
+
+func reload(ctx context.Context, c adminrpc.AdminSwapperClient, args []string) error {
+	_, err := c.ReloadConfig(ctx, &adminrpc.ReloadConfigRequest{})
+	return err
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// config holds the settings which can change while running. A config is
+// never modified once in use: a reload builds a new one and swaps it in.
+type config struct {
+	lockHeightMin     int64
+	lockHeightMax     int64
+	lockHeightDefault int64
+	// claimMargin is the number of blocks left, beyond safeDepth, for the
+	// claim of a CLTV swap to confirm before its refund opens.
+	claimMargin int64
+
+	// safeDepth is the number of confirmations after which a deposit is
+	// considered final and the invoice of its swap can be paid.
+	safeDepth int32
+
+	zeroConf zeroConfPolicy
+
+	// feeSchedule is sorted by minAmount. The first tier applies to the
+	// smaller amounts too.
+	feeSchedule []feeTier
+	quoteTTL    time.Duration
+
+	minSwapAmount btcutil.Amount
+	maxSwapAmount btcutil.Amount
+	// liquidityCheck rejects swaps the lnd node can't pay with its
+	// current outbound liquidity.
+	liquidityCheck bool
+
+	// requireAuth rejects the requests without a valid credential.
+	requireAuth bool
+
+	// credentialLimiter and ipLimiter limit the swap creations per
+	// credential and per IP. They are carried over by the reloads which
+	// don't change their rate.
+	credentialLimiter *limiter
+	ipLimiter         *limiter
+	// maxUnfundedSwaps caps the swaps of a client waiting for a deposit, 0
+	// for no cap.
+	maxUnfundedSwaps int64
+	// unfundedSwapTTL is how long a swap may wait for its first deposit
+	// before it expires.
+	unfundedSwapTTL time.Duration
+
+	// chainTipMaxAge is how long the tip of a chain backend may stay at the
+	// same height before the backend is considered stale. 0 disables the
+	// check.
+	chainTipMaxAge time.Duration
+}
+
+// defaultConfig is in use until the config is first loaded.
+var defaultConfig = config{
+	lockHeightMin:     144,
+	lockHeightMax:     2016,
+	lockHeightDefault: defaultLockHeight,
+	claimMargin:       6,
+	safeDepth:         3,
+	zeroConf:          zeroConfPolicy{minFeeRatePercent: 100},
+	feeSchedule:       []feeTier{{}},
+	quoteTTL:          defaultQuoteTTL,
+	minSwapAmount:     10000,
+	maxSwapAmount:     4000000,
+	liquidityCheck:    true,
+	requireAuth:       true,
+	credentialLimiter: newLimiter(60),
+	ipLimiter:         newLimiter(20),
+	maxUnfundedSwaps:  20,
+	unfundedSwapTTL:   24 * time.Hour,
+	chainTipMaxAge:    2 * time.Hour,
+}
+
+var activeConfig atomic.Pointer[config]
+
+// currentConfig returns the config in use. Callers read it once and use the
+// same value throughout, so that a reload can't mix two configs.
+func currentConfig() *config {
+	if c := activeConfig.Load(); c != nil {
+		return c
+	}
+	return &defaultConfig
+}
+
+// configLoaders read the settings which can change while running into a
+// config and validate them. The networks and the listen addresses need a
+// restart.
+var configLoaders = []struct {
+	name string
+	load func(c *config) error
+}{
+	{"loadLockHeightBounds", loadLockHeightBounds},
+	{"loadSafeDepth", loadSafeDepth},
+	{"loadZeroConfPolicy", loadZeroConfPolicy},
+	{"loadFeeSchedule", loadFeeSchedule},
+	{"loadSwapLimits", loadSwapLimits},
+	{"loadAuth", loadAuth},
+	{"loadAbuseLimits", loadAbuseLimits},
+	{"loadHealthConfig", loadHealthConfig},
+}
+
+// configMu serializes the config reloads.
+var configMu sync.Mutex
+
+// loadConfig reads CONFIG_FILE, if set, into the environment, then runs the
+// config loaders on a copy of the current config, which replaces it only if
+// all of them succeed. A variable removed from the file keeps its value.
+func loadConfig() error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if name := os.Getenv("CONFIG_FILE"); name != "" {
+		if err := readConfigFile(name); err != nil {
+			return err
+		}
+	}
+	c := *currentConfig()
+	for _, l := range configLoaders {
+		if err := l.load(&c); err != nil {
+			return fmt.Errorf("%v() failed: %w", l.name, err)
+		}
+	}
+	activeConfig.Store(&c)
+	return nil
+}
+
+// reloadConfig runs loadConfig again and logs the outcome.
+func reloadConfig() error {
+	if err := loadConfig(); err != nil {
+		slog.Error("config reload failed", "error", err)
+		return err
+	}
+	slog.Info("config reloaded")
+	return nil
+}
+
+// readConfigFile sets the environment variables of the file name, one
+// NAME=value per line. Empty lines and lines starting with # are skipped.
+// Nothing is set if the file has an invalid line.
+func readConfigFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var vars [][2]string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(s, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("%v:%v: want NAME=value", name, line)
+		}
+		vars = append(vars, [2]string{key, strings.TrimSpace(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for _, v := range vars {
+		if err := os.Setenv(v[0], v[1]); err != nil {
+			return fmt.Errorf("%v: %v: %w", name, v[0], err)
+		}
+	}
+	return nil
+}
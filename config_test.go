@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestLoadConfigInvalid(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("MIN_SWAP_AMOUNT", "5000")
+	t.Setenv("MAX_SWAP_AMOUNT", "1000")
+	t.Setenv("SAFE_DEPTH", "6")
+
+	before := currentConfig()
+	if err := loadConfig(); err == nil {
+		t.Fatal("min above max accepted")
+	}
+	// Nothing is applied, not even the settings loaded before the failure.
+	if currentConfig() != before {
+		t.Fatal("config replaced by a failed load")
+	}
+	if currentConfig().safeDepth == 6 {
+		t.Fatal("SAFE_DEPTH applied by a failed load")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+)
+
+const (
+	defaultQuoteTTL = 10 * time.Minute
+	quoteIDLen      = 16
+	// p2wpkhOutputSize is the size of the output of a redeem transaction
+	// paying to a P2WPKH address: value, script length and script.
+	p2wpkhOutputSize = 8 + 1 + 22
+)
+
+// feeTier is the service fee charged for swaps of at least minAmount:
+// baseFee plus rate parts per million of the amount.
+type feeTier struct {
+	minAmount btcutil.Amount
+	baseFee   btcutil.Amount
+	ratePPM   int64
+}
+
+// loadFeeSchedule reads FEE_SCHEDULE, a comma separated list of
+// minAmount:baseFee:ratePPM tiers in satoshis, e.g.
+// "0:1000:5000,1000000:2000:3000", and QUOTE_TTL. Without FEE_SCHEDULE no
+// service fee is charged.
+func loadFeeSchedule(c *config) error {
+	if s := os.Getenv("FEE_SCHEDULE"); s != "" {
+		var schedule []feeTier
+		for _, t := range strings.Split(s, ",") {
+			fields := strings.Split(strings.TrimSpace(t), ":")
+			if len(fields) != 3 {
+				return fmt.Errorf("FEE_SCHEDULE tier %q: want minAmount:baseFee:ratePPM", t)
+			}
+			var values [3]int64
+			for i, f := range fields {
+				v, err := strconv.ParseInt(f, 10, 64)
+				if err != nil || v < 0 {
+					return fmt.Errorf("FEE_SCHEDULE tier %q: %q not valid", t, f)
+				}
+				values[i] = v
+			}
+			schedule = append(schedule, feeTier{
+				minAmount: btcutil.Amount(values[0]),
+				baseFee:   btcutil.Amount(values[1]),
+				ratePPM:   values[2],
+			})
+		}
+		sort.Slice(schedule, func(i, j int) bool {
+			return schedule[i].minAmount < schedule[j].minAmount
+		})
+		c.feeSchedule = schedule
+	}
+
+	if s := os.Getenv("QUOTE_TTL"); s != "" {
+		ttl, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("QUOTE_TTL=%v: %w", s, err)
+		}
+		c.quoteTTL = ttl
+	}
+	return nil
+}
+
+// serviceFee returns the service fee of a swap of amount.
+func serviceFee(amount btcutil.Amount) btcutil.Amount {
+	schedule := currentConfig().feeSchedule
+	tier := schedule[0]
+	for _, t := range schedule[1:] {
+		if amount < t.minAmount {
+			break
+		}
+		tier = t
+	}
+	return tier.baseFee + amount*btcutil.Amount(tier.ratePPM)/1e6
+}
+
+// estimateRedeemFee returns the miner fee of a redeem transaction spending a
+// single deposit at feePerKw.
+func estimateRedeemFee(feePerKw chainfee.SatPerKWeight) btcutil.Amount {
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	weight := 4*(tx.SerializeSizeStripped()+p2wpkhOutputSize) + redeemWitnessInputSize
+	return feePerKw.FeeForWeight(int64(weight))
+}
+
+// swapQuote locks the pricing of a swap of amount until expiry.
+type swapQuote struct {
+	id         []byte
+	network    string
+	amount     btcutil.Amount
+	serviceFee btcutil.Amount
+	// feeRate is the miner fee rate, in sat/vbyte, charged for the redeem
+	// transaction.
+	feeRate  uint64
+	minerFee btcutil.Amount
+	expiry   time.Time
+}
+
+// total returns what the payer deposits: the amount and all the fees.
+func (q *swapQuote) total() btcutil.Amount {
+	return q.amount + q.serviceFee + q.minerFee
+}
+
+// newQuote prices a swap of amount on net at the current fee rate and stores
+// the quote.
+func newQuote(ctx context.Context, net *chaincfg.Params, amount btcutil.Amount) (*swapQuote, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("amount %v not valid", amount)
+	}
+	if err := admitSwap(ctx, amount); err != nil {
+		return nil, err
+	}
+	c, err := chainClient(net)
+	if err != nil {
+		return nil, err
+	}
+	feeRate, err := c.RecommendedFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &swapQuote{
+		id:         make([]byte, quoteIDLen),
+		network:    net.Name,
+		amount:     amount,
+		serviceFee: serviceFee(amount),
+		feeRate:    feeRate,
+		minerFee:   estimateRedeemFee(chainfee.SatPerKVByte(feeRate * 1000).FeePerKWeight()),
+		expiry:     time.Now().Add(currentConfig().quoteTTL),
+	}
+	if _, err := rand.Read(q.id); err != nil {
+		return nil, err
+	}
+	if err := saveSwapQuote(ctx, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	healthCheckInterval = 15 * time.Second
+	healthCheckTimeout  = 10 * time.Second
+)
+
+// loadHealthConfig reads CHAIN_TIP_MAX_AGE (default 2h, 0 to disable).
+func loadHealthConfig(c *config) error {
+	if s := os.Getenv("CHAIN_TIP_MAX_AGE"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("CHAIN_TIP_MAX_AGE=%v: %w", s, err)
+		}
+		c.chainTipMaxAge = d
+	}
+	return nil
+}
+
+// tip is the last height seen on a network and when it was first seen.
+type tip struct {
+	height uint32
+	seen   time.Time
+}
+
+// healthChecker periodically checks the dependencies of the swapper and
+// reports the result to the gRPC health service and to /readyz.
+type healthChecker struct {
+	grpcHealth *health.Server
+
+	mu     sync.Mutex
+	tips   map[string]tip
+	errors map[string]string
+	ready  bool
+}
+
+func newHealthChecker() *healthChecker {
+	h := &healthChecker{
+		grpcHealth: health.NewServer(),
+		tips:       make(map[string]tip),
+	}
+	h.setServing(false)
+	return h
+}
+
+// run checks the dependencies every healthCheckInterval until ctx is done.
+func (h *healthChecker) run(ctx context.Context) {
+	t := time.NewTicker(healthCheckInterval)
+	defer t.Stop()
+	for {
+		h.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// check runs every check and records their errors. The swapper is ready
+// when all of them pass.
+func (h *healthChecker) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	errs := make(map[string]string)
+	if err := pgxPool.Ping(ctx); err != nil {
+		errs["postgres"] = err.Error()
+	}
+	if err := checkLnd(ctx); err != nil {
+		errs["lnd"] = err.Error()
+	}
+	for name, n := range networks {
+		if err := h.checkChain(ctx, n); err != nil {
+			errs["chain "+name] = err.Error()
+		}
+	}
+
+	ready := len(errs) == 0
+	h.mu.Lock()
+	changed := ready != h.ready
+	h.errors = errs
+	h.ready = ready
+	h.mu.Unlock()
+	if changed {
+		slog.Warn("readiness changed", "ready", ready, "errors", errs)
+	}
+	h.setServing(ready)
+}
+
+func (h *healthChecker) setServing(serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	h.grpcHealth.SetServingStatus("", status)
+	h.grpcHealth.SetServingStatus("submarineswaprpc.SubmarineSwapper", status)
+}
+
+// checkLnd checks that lnd answers and is synced to the chain.
+func checkLnd(ctx context.Context) error {
+	info, err := lightning.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return err
+	}
+	if !info.SyncedToChain {
+		return errors.New("not synced to chain")
+	}
+	return nil
+}
+
+// checkChain checks that the backend of n answers and that its tip moved
+// within chainTipMaxAge.
+func (h *healthChecker) checkChain(ctx context.Context, n *network) error {
+	height, err := n.backend.CurrentHeight(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.tips[n.params.Name]
+	if !ok || height != t.height {
+		h.tips[n.params.Name] = tip{height: height, seen: now}
+		return nil
+	}
+	if maxAge := currentConfig().chainTipMaxAge; maxAge > 0 && now.Sub(t.seen) > maxAge {
+		return fmt.Errorf("tip stuck at height %v since %v", height, t.seen.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// healthz reports that the process is alive.
+func (h *healthChecker) healthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok\n"))
+}
+
+// readyz reports the result of the last check, with the failing checks.
+func (h *healthChecker) readyz(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	ready, errs := h.ready, h.errors
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(struct {
+		Ready  bool              `json:"ready"`
+		Errors map[string]string `json:"errors,omitempty"`
+	}{ready, errs})
+}
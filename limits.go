@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// lightning is the lnd node paying the swap invoices.
+var lightning lnrpc.LightningClient
+
+// loadSwapLimits reads MIN_SWAP_AMOUNT and MAX_SWAP_AMOUNT, in satoshis
+// (default 10000 and 4000000), and LIQUIDITY_CHECK (default true).
+func loadSwapLimits(c *config) error {
+	for _, v := range []struct {
+		name  string
+		value *btcutil.Amount
+	}{
+		{"MIN_SWAP_AMOUNT", &c.minSwapAmount},
+		{"MAX_SWAP_AMOUNT", &c.maxSwapAmount},
+	} {
+		s := os.Getenv(v.name)
+		if s == "" {
+			continue
+		}
+		a, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%v=%v: %w", v.name, s, err)
+		}
+		*v.value = btcutil.Amount(a)
+	}
+	if c.minSwapAmount < 0 || c.minSwapAmount > c.maxSwapAmount {
+		return fmt.Errorf("invalid swap amount limits [%v, %v]", c.minSwapAmount, c.maxSwapAmount)
+	}
+
+	if s := os.Getenv("LIQUIDITY_CHECK"); s != "" {
+		check, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("LIQUIDITY_CHECK=%v: %w", s, err)
+		}
+		c.liquidityCheck = check
+	}
+	return nil
+}
+
+// outboundLiquidity returns what the lnd node can still send: the local
+// balance above the channel reserve of its active channels, less the amounts
+// of the funded swaps not yet redeemed, which are about to be paid. Pending
+// HTLCs are already excluded from the local balance by lnd.
+func outboundLiquidity(ctx context.Context) (btcutil.Amount, error) {
+	res, err := lightning.ListChannels(ctx, &lnrpc.ListChannelsRequest{ActiveOnly: true})
+	if err != nil {
+		return 0, fmt.Errorf("lnd ListChannels: %w", err)
+	}
+	var liquidity btcutil.Amount
+	for _, c := range res.Channels {
+		spendable := c.LocalBalance
+		if c.LocalConstraints != nil {
+			spendable -= int64(c.LocalConstraints.ChanReserveSat)
+		}
+		if spendable > 0 {
+			liquidity += btcutil.Amount(spendable)
+		}
+	}
+
+	committed, err := getCommittedAmount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	liquidity -= committed
+	if liquidity < 0 {
+		liquidity = 0
+	}
+	return liquidity, nil
+}
+
+// swapLimits returns the smallest and the largest swap amount that can be
+// served now. The largest is the configured maximum capped by the outbound
+// liquidity when the liquidity check is enabled.
+func swapLimits(ctx context.Context) (min, max btcutil.Amount, err error) {
+	cfg := currentConfig()
+	max = cfg.maxSwapAmount
+	if cfg.liquidityCheck {
+		var liquidity btcutil.Amount
+		liquidity, err = outboundLiquidity(ctx)
+		if err != nil {
+			return
+		}
+		if liquidity < max {
+			max = liquidity
+		}
+	}
+	return cfg.minSwapAmount, max, nil
+}
+
+// admitSwap checks that a swap of amount can be served. An amount of 0,
+// unknown, is only checked against the liquidity for the minimum amount.
+func admitSwap(ctx context.Context, amount btcutil.Amount) error {
+	cfg := currentConfig()
+	if amount != 0 && amount < cfg.minSwapAmount {
+		return status.Errorf(codes.OutOfRange, "amount below the minimum of %v", int64(cfg.minSwapAmount))
+	}
+	if amount > cfg.maxSwapAmount {
+		return status.Errorf(codes.OutOfRange, "amount above the maximum of %v", int64(cfg.maxSwapAmount))
+	}
+	if !cfg.liquidityCheck {
+		return nil
+	}
+	liquidity, err := outboundLiquidity(ctx)
+	if err != nil {
+		return err
+	}
+	need := amount
+	if need == 0 {
+		need = cfg.minSwapAmount
+	}
+	if need > liquidity {
+		return status.Error(codes.ResourceExhausted, "not enough liquidity to serve the swap")
+	}
+	return nil
+}
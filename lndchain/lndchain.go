@@ -0,0 +1,525 @@
+// Package lndchain implements the chain interfaces over the ChainNotifier and
+// WalletKit sub-servers of lnd, so that swaps can be followed without any
+// third party explorer. lnd must be built with the chainrpc and walletrpc
+// tags.
+//
+// lnd can't list the outputs of an address. Instead, each tracked address is
+// followed with confirmation notifications on its script and each output
+// found is then followed with a spend notification. Hence GetUtxos only knows
+// the addresses tracked since startup, and deposits are only delivered once
+// confirmed, and again as unconfirmed if their block is reorganized out.
+package lndchain
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"swapper/chain"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"google.golang.org/grpc"
+)
+
+const (
+	// feeConfTarget is the lowest target lnd estimates fees for.
+	feeConfTarget = 2
+	// blockInterval is the expected time between blocks, used to estimate
+	// the height at which an address was created.
+	blockInterval = 10 * time.Minute
+)
+
+// Backend implements chain.Backend and chain.Notifier.
+type Backend struct {
+	lightning lnrpc.LightningClient
+	notifier  chainrpc.ChainNotifierClient
+	wallet    walletrpc.WalletKitClient
+	params    *chaincfg.Params
+	// lookback is how many blocks before the estimated creation height of
+	// an address lnd searches for its deposits.
+	lookback uint32
+
+	blocks   chan chain.Block
+	deposits chan chain.Deposit
+
+	mu      sync.Mutex
+	ctx     context.Context
+	tracked map[string]*trackedAddress
+}
+
+type trackedAddress struct {
+	address    string
+	script     []byte
+	heightHint uint32
+	utxos      map[wire.OutPoint]chain.Utxo
+	spends     map[wire.OutPoint]*chain.Spend
+	spent      map[wire.OutPoint]chain.Utxo
+	// watched are the outputs followed with a spend notification.
+	watched map[wire.OutPoint]struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+var (
+	_ chain.Backend     = (*Backend)(nil)
+	_ chain.Notifier    = (*Backend)(nil)
+	_ chain.SpendFinder = (*Backend)(nil)
+)
+
+// New returns a backend using the lnd behind conn, which must run on params.
+// Deposits to tracked addresses are searched from lookback blocks below the
+// height at which the address was created, estimated from its creation time.
+func New(conn *grpc.ClientConn, params *chaincfg.Params, lookback uint32) *Backend {
+	return &Backend{
+		lightning: lnrpc.NewLightningClient(conn),
+		notifier:  chainrpc.NewChainNotifierClient(conn),
+		wallet:    walletrpc.NewWalletKitClient(conn),
+		params:    params,
+		lookback:  lookback,
+		blocks:    make(chan chain.Block, 16),
+		deposits:  make(chan chain.Deposit, 256),
+		tracked:   make(map[string]*trackedAddress),
+	}
+}
+
+// RecommendedFee implements chain.Backend with WalletKit.EstimateFee.
+func (b *Backend) RecommendedFee(ctx context.Context) (uint64, error) {
+	res, err := b.wallet.EstimateFee(ctx, &walletrpc.EstimateFeeRequest{ConfTarget: feeConfTarget})
+	if err != nil {
+		return 0, fmt.Errorf("lnd EstimateFee: %w", err)
+	}
+	// sat/kw to sat/vB.
+	return uint64(math.Ceil(float64(res.SatPerKw) * 4 / 1000)), nil
+}
+
+// GetUtxos implements chain.Backend for tracked addresses.
+func (b *Backend) GetUtxos(ctx context.Context, address btcutil.Address) ([]chain.Utxo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tracked[address.EncodeAddress()]
+	if !ok {
+		return nil, fmt.Errorf("lnd: address %v is not tracked", address)
+	}
+	utxos := make([]chain.Utxo, 0, len(t.utxos))
+	for _, u := range t.utxos {
+		utxos = append(utxos, u)
+	}
+	return utxos, nil
+}
+
+// GetSpend implements chain.SpendFinder for the outputs paying to tracked
+// addresses. lnd only reports confirmed spends.
+func (b *Backend) GetSpend(ctx context.Context, op wire.OutPoint) (*chain.Spend, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range b.tracked {
+		if spend, ok := t.spends[op]; ok {
+			return spend, nil
+		}
+		if _, ok := t.utxos[op]; ok {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("lnd: output %v is not tracked", op)
+}
+
+// BroadcastTransaction implements chain.Backend with
+// WalletKit.PublishTransaction.
+func (b *Backend) BroadcastTransaction(ctx context.Context, tx *wire.MsgTx) (*chainhash.Hash, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	res, err := b.wallet.PublishTransaction(ctx, &walletrpc.Transaction{TxHex: buf.Bytes()})
+	if err != nil {
+		return nil, fmt.Errorf("lnd PublishTransaction: %w", err)
+	}
+	if res.PublishError != "" {
+		return nil, fmt.Errorf("lnd PublishTransaction: %v", res.PublishError)
+	}
+	txid := tx.TxHash()
+	return &txid, nil
+}
+
+// CurrentHeight implements chain.Backend with Lightning.GetInfo.
+func (b *Backend) CurrentHeight(ctx context.Context) (uint32, error) {
+	info, err := b.lightning.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("lnd GetInfo: %w", err)
+	}
+	return info.BlockHeight, nil
+}
+
+// Blocks implements chain.Notifier. lnd doesn't report the previous block
+// hash, so PrevHash is left zero.
+func (b *Backend) Blocks() <-chan chain.Block {
+	return b.blocks
+}
+
+// Deposits implements chain.Notifier.
+func (b *Backend) Deposits() <-chan chain.Deposit {
+	return b.deposits
+}
+
+// Start follows the tracked addresses until ctx is done. Addresses are only
+// followed once Start or Run is called, which a backend serving as a
+// provider but not as the notifier must do for GetUtxos and GetSpend to find
+// their outputs.
+func (b *Backend) Start(ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx != nil {
+		return
+	}
+	b.ctx = ctx
+	for _, t := range b.tracked {
+		b.startLocked(t)
+	}
+}
+
+// Run implements chain.Notifier with ChainNotifier.RegisterBlockEpochNtfn.
+// It starts the backend as Start does.
+func (b *Backend) Run(ctx context.Context) error {
+	b.Start(ctx)
+
+	stream, err := b.notifier.RegisterBlockEpochNtfn(ctx, &chainrpc.BlockEpoch{})
+	if err != nil {
+		return fmt.Errorf("lnd RegisterBlockEpochNtfn: %w", err)
+	}
+	for {
+		epoch, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("lnd block epochs: %w", err)
+		}
+		hash, err := chainhash.NewHash(epoch.Hash)
+		if err != nil {
+			return err
+		}
+		select {
+		case b.blocks <- chain.Block{Hash: *hash, Height: int32(epoch.Height)}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TrackAddress implements chain.AddressTracker.
+func (b *Backend) TrackAddress(address string, created time.Time) error {
+	b.mu.Lock()
+	_, ok := b.tracked[address]
+	b.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	addr, err := btcutil.DecodeAddress(address, b.params)
+	if err != nil {
+		return err
+	}
+	script, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	height, err := b.CurrentHeight(context.Background())
+	if err != nil {
+		return err
+	}
+	back := b.lookback
+	if since := time.Since(created); since > 0 {
+		back += uint32(since / blockInterval)
+	}
+	heightHint := uint32(1)
+	if height > back {
+		heightHint = height - back
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.tracked[address]; ok {
+		return nil
+	}
+	t := &trackedAddress{
+		address:    address,
+		script:     script,
+		heightHint: heightHint,
+		utxos:      make(map[wire.OutPoint]chain.Utxo),
+		spends:     make(map[wire.OutPoint]*chain.Spend),
+		spent:      make(map[wire.OutPoint]chain.Utxo),
+		watched:    make(map[wire.OutPoint]struct{}),
+	}
+	b.tracked[address] = t
+	if b.ctx != nil {
+		b.startLocked(t)
+	}
+	return nil
+}
+
+// UntrackAddress implements chain.AddressTracker.
+func (b *Backend) UntrackAddress(address string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tracked[address]
+	if !ok {
+		return nil
+	}
+	if t.cancel != nil {
+		t.cancel()
+	}
+	delete(b.tracked, address)
+	return nil
+}
+
+// startLocked starts following t. b.mu must be held.
+func (b *Backend) startLocked(t *trackedAddress) {
+	if t.cancel != nil {
+		return
+	}
+	t.ctx, t.cancel = context.WithCancel(b.ctx)
+	go func() {
+		err := b.watchAddress(t.ctx, t)
+		if err != nil && t.ctx.Err() == nil {
+			slog.Warn("lnd watch failed", "address", t.address, "error", err)
+		}
+	}()
+}
+
+// watchAddress waits for the transactions paying to t. Whenever a block
+// holding some of them is reorganized out, their outputs are delivered as
+// unconfirmed deposits and the search starts again from t.heightHint.
+func (b *Backend) watchAddress(ctx context.Context, t *trackedAddress) error {
+	for {
+		scanCtx, cancel := context.WithCancel(ctx)
+		reorged := make(chan uint32, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- b.scanAddress(scanCtx, t, reorged)
+		}()
+
+		select {
+		case err := <-done:
+			cancel()
+			return err
+		case height := <-reorged:
+			cancel()
+			<-done
+			if err := b.dropDeposits(ctx, t, height); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// scanAddress delivers the transactions paying to t confirmed from
+// t.heightHint. A confirmation request only reports the first transaction
+// found, so its whole block is requested and searched for the others, and
+// the next request starts at the next block. Each request is kept open to
+// send on reorged the height of its block if that block is reorganized out.
+func (b *Backend) scanAddress(ctx context.Context, t *trackedAddress, reorged chan<- uint32) error {
+	heightHint := t.heightHint
+	for {
+		stream, err := b.notifier.RegisterConfirmationsNtfn(ctx, &chainrpc.ConfRequest{
+			Script:       t.script,
+			NumConfs:     1,
+			HeightHint:   heightHint,
+			IncludeBlock: true,
+		})
+		if err != nil {
+			return fmt.Errorf("RegisterConfirmationsNtfn: %w", err)
+		}
+
+		var conf *chainrpc.ConfDetails
+		for conf == nil {
+			event, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			conf = event.GetConf()
+		}
+		if err := b.handleConf(ctx, t, conf); err != nil {
+			return err
+		}
+
+		go func(height uint32) {
+			for {
+				event, err := stream.Recv()
+				if err != nil {
+					return
+				}
+				if event.GetReorg() != nil {
+					select {
+					case reorged <- height:
+					default:
+					}
+					return
+				}
+			}
+		}(conf.BlockHeight)
+		heightHint = conf.BlockHeight + 1
+	}
+}
+
+// handleConf records and delivers the outputs paying to t in the block of
+// conf, or only in its transaction if lnd didn't include the block.
+func (b *Backend) handleConf(ctx context.Context, t *trackedAddress, conf *chainrpc.ConfDetails) error {
+	var txs []*wire.MsgTx
+	if len(conf.RawBlock) > 0 {
+		var block wire.MsgBlock
+		if err := block.Deserialize(bytes.NewReader(conf.RawBlock)); err != nil {
+			return err
+		}
+		txs = block.Transactions
+	} else {
+		var tx wire.MsgTx
+		if err := tx.Deserialize(bytes.NewReader(conf.RawTx)); err != nil {
+			return err
+		}
+		txs = []*wire.MsgTx{&tx}
+	}
+	blockHash, err := chainhash.NewHash(conf.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		txHash := tx.TxHash()
+		for vout, out := range tx.TxOut {
+			if !bytes.Equal(out.PkScript, t.script) {
+				continue
+			}
+			op := *wire.NewOutPoint(&txHash, uint32(vout))
+			b.mu.Lock()
+			if _, ok := t.spends[op]; !ok {
+				t.utxos[op] = chain.Utxo{
+					Value:       btcutil.Amount(out.Value),
+					BlockHeight: int32(conf.BlockHeight),
+					OutPoint:    op,
+				}
+			}
+			_, watched := t.watched[op]
+			t.watched[op] = struct{}{}
+			b.mu.Unlock()
+
+			if !watched {
+				go func() {
+					err := b.watchSpend(t.ctx, t, op, conf.BlockHeight)
+					if err != nil && t.ctx.Err() == nil {
+						slog.Warn("lnd watch spend failed", "outpoint", op, "error", err)
+					}
+					b.mu.Lock()
+					delete(t.watched, op)
+					b.mu.Unlock()
+				}()
+			}
+
+			select {
+			case b.deposits <- chain.Deposit{
+				Address:     t.address,
+				OutPoint:    op,
+				Value:       btcutil.Amount(out.Value),
+				Confirmed:   true,
+				BlockHeight: int32(conf.BlockHeight),
+				BlockHash:   *blockHash,
+			}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// dropDeposits forgets the outputs paying to t confirmed at or above height,
+// which was reorganized out, and delivers them as unconfirmed deposits. Those
+// still confirmed are found again by the next scan.
+func (b *Backend) dropDeposits(ctx context.Context, t *trackedAddress, height uint32) error {
+	var dropped []chain.Utxo
+	b.mu.Lock()
+	for op, u := range t.utxos {
+		if u.BlockHeight >= int32(height) {
+			dropped = append(dropped, u)
+			delete(t.utxos, op)
+		}
+	}
+	for op, u := range t.spent {
+		if u.BlockHeight >= int32(height) {
+			dropped = append(dropped, u)
+			delete(t.spent, op)
+			delete(t.spends, op)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, u := range dropped {
+		select {
+		case b.deposits <- chain.Deposit{
+			Address:  t.address,
+			OutPoint: u.OutPoint,
+			Value:    u.Value,
+		}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// watchSpend moves op from the utxos of t to its spends once it is spent,
+// and back if the spend is reorged out.
+func (b *Backend) watchSpend(ctx context.Context, t *trackedAddress, op wire.OutPoint, heightHint uint32) error {
+	stream, err := b.notifier.RegisterSpendNtfn(ctx, &chainrpc.SpendRequest{
+		Outpoint:   &chainrpc.Outpoint{Hash: op.Hash[:], Index: op.Index},
+		Script:     t.script,
+		HeightHint: heightHint,
+	})
+	if err != nil {
+		return fmt.Errorf("RegisterSpendNtfn: %w", err)
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		var spend *chain.Spend
+		if details := event.GetSpend(); details != nil {
+			var tx wire.MsgTx
+			if err := tx.Deserialize(bytes.NewReader(details.RawSpendingTx)); err != nil {
+				return err
+			}
+			spend = &chain.Spend{
+				Tx:          &tx,
+				InputIndex:  details.SpendingInputIndex,
+				Confirmed:   true,
+				BlockHeight: int32(details.SpendingHeight),
+			}
+		}
+
+		b.mu.Lock()
+		switch {
+		case spend != nil:
+			if u, ok := t.utxos[op]; ok {
+				t.spends[op] = spend
+				delete(t.utxos, op)
+				// Keep the output to restore it on reorg.
+				t.spent[op] = u
+			}
+		case event.GetReorg() != nil:
+			if u, ok := t.spent[op]; ok {
+				t.utxos[op] = u
+				delete(t.spends, op)
+				delete(t.spent, op)
+			}
+		}
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"swapper/swapscript"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+const (
+	// maxCSVLockHeight is the largest block based relative lock time
+	// (BIP 68 keeps 16 bits for the value).
+	maxCSVLockHeight = 0xffff
+)
+
+// loadLockHeightBounds reads the operator bounds for the swap CSV delay from
+// MIN_LOCK_HEIGHT, MAX_LOCK_HEIGHT and DEFAULT_LOCK_HEIGHT, and CLAIM_MARGIN
+// (default 6).
+func loadLockHeightBounds(c *config) error {
+	for _, v := range []struct {
+		name  string
+		value *int64
+	}{
+		{"MIN_LOCK_HEIGHT", &c.lockHeightMin},
+		{"MAX_LOCK_HEIGHT", &c.lockHeightMax},
+		{"DEFAULT_LOCK_HEIGHT", &c.lockHeightDefault},
+		{"CLAIM_MARGIN", &c.claimMargin},
+	} {
+		s := os.Getenv(v.name)
+		if s == "" {
+			continue
+		}
+		h, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%v=%v: %w", v.name, s, err)
+		}
+		*v.value = h
+	}
+
+	if c.lockHeightMin < 1 || c.lockHeightMax > maxCSVLockHeight || c.lockHeightMin > c.lockHeightMax {
+		return fmt.Errorf("invalid lock height bounds [%v, %v]", c.lockHeightMin, c.lockHeightMax)
+	}
+	if c.claimMargin < 0 {
+		return fmt.Errorf("CLAIM_MARGIN=%v not valid", c.claimMargin)
+	}
+	if c.lockHeightDefault < c.lockHeightMin || c.lockHeightDefault > c.lockHeightMax {
+		return fmt.Errorf("default lock height %v not in [%v, %v]",
+			c.lockHeightDefault, c.lockHeightMin, c.lockHeightMax)
+	}
+	return nil
+}
+
+// chooseLockHeight returns the lock height to use for a swap. A zero
+// requested value selects the default; any other value must be within the
+// operator bounds.
+func chooseLockHeight(requested int64) (int64, error) {
+	cfg := currentConfig()
+	if requested == 0 {
+		return cfg.lockHeightDefault, nil
+	}
+	if requested < cfg.lockHeightMin || requested > cfg.lockHeightMax {
+		return 0, fmt.Errorf("lock height %v not in [%v, %v]",
+			requested, cfg.lockHeightMin, cfg.lockHeightMax)
+	}
+	return requested, nil
+}
+
+// chooseLockTime returns the absolute refund height of a CLTV swap given the
+// current chain height. A zero requested value selects currentHeight plus the
+// default delay; any other value must leave a delay within the operator
+// bounds.
+func chooseLockTime(requested, currentHeight int64) (int64, error) {
+	cfg := currentConfig()
+	if requested == 0 {
+		return currentHeight + cfg.lockHeightDefault, nil
+	}
+	if requested >= txscript.LockTimeThreshold {
+		return 0, fmt.Errorf("lock time %v is not a block height", requested)
+	}
+	delay := requested - currentHeight
+	if delay < cfg.lockHeightMin || delay > cfg.lockHeightMax {
+		return 0, fmt.Errorf("lock time %v is %v blocks away, not in [%v, %v]",
+			requested, delay, cfg.lockHeightMin, cfg.lockHeightMax)
+	}
+	return requested, nil
+}
+
+// checkRefundDistance returns an error if the refund of the swap script opens
+// too soon after currentHeight for a claim to reach safe depth before: its
+// invoice must not be paid anymore. Only CLTV swaps have a refund height
+// known before the deposit confirms.
+func checkRefundDistance(script []byte, currentHeight int64) error {
+	s, err := swapscript.Parse(script)
+	if err != nil {
+		return err
+	}
+	if s.Type != swapscript.CLTV {
+		return nil
+	}
+	cfg := currentConfig()
+	if left := s.LockHeight - currentHeight; left < int64(cfg.safeDepth)+cfg.claimMargin {
+		return fmt.Errorf("refund opens in %v blocks, at height %v", left, s.LockHeight)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/sha256"
+	"swapper/swapscript"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+func TestCheckRefundDistance(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := key.PubKey().SerializeCompressed()
+	hash := sha256.Sum256([]byte("preimage"))
+	csv, err := swapscript.New(swapscript.CSV, pubKey, pubKey, hash[:], 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cltv, err := swapscript.New(swapscript.CLTV, pubKey, pubKey, hash[:], 800000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the default config the refund must be at least 3+6 blocks away.
+	for _, tt := range []struct {
+		name   string
+		script []byte
+		height int64
+		ok     bool
+	}{
+		{"cltv far", cltv, 799000, true},
+		{"cltv at margin", cltv, 800000 - 9, true},
+		{"cltv within margin", cltv, 800000 - 8, false},
+		{"cltv refundable", cltv, 800001, false},
+		{"csv", csv, 800001, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRefundDistance(tt.script, tt.height)
+			if (err == nil) != tt.ok {
+				t.Errorf("checkRefundDistance() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+
+	if err := checkRefundDistance([]byte{1, 2, 3}, 0); err == nil {
+		t.Error("checkRefundDistance() accepted a script not parsed")
+	}
+}
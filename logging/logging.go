@@ -0,0 +1,81 @@
+// Package logging configures the structured logger of the swapper and keeps
+// secrets out of the logs.
+package logging
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are the attribute keys, lower cased and without underscores,
+// whose values are redacted whatever their type.
+var secretKeys = map[string]bool{
+	"key":        true,
+	"privatekey": true,
+	"swapperkey": true,
+	"servicekey": true,
+	"preimage":   true,
+	"secret":     true,
+	"apikey":     true,
+	"macaroon":   true,
+	"password":   true,
+	"pass":       true,
+}
+
+// ReplaceAttr redacts the attributes with a secret key, e.g. swapper_key,
+// and logs byte slices in hex. It is installed in the handlers returned by
+// New.
+func ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if secretKeys[strings.ReplaceAll(strings.ToLower(a.Key), "_", "")] {
+		return slog.String(a.Key, redacted)
+	}
+	if a.Value.Kind() == slog.KindAny {
+		if b, ok := a.Value.Any().([]byte); ok {
+			return slog.String(a.Key, hex.EncodeToString(b))
+		}
+	}
+	return a
+}
+
+// New returns a logger writing to w at level, as JSON or text.
+func New(w io.Writer, level slog.Leveler, json bool) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: ReplaceAttr}
+	if json {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Setup reads LOG_LEVEL (debug, info, warn or error, default info) and
+// LOG_FORMAT (text or json, default text) and makes the logger they describe,
+// writing to stderr, the default logger.
+func Setup() error {
+	var level slog.Level
+	if s := os.Getenv("LOG_LEVEL"); s != "" {
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("LOG_LEVEL=%v: %w", s, err)
+		}
+	}
+	var json bool
+	switch s := os.Getenv("LOG_FORMAT"); s {
+	case "", "text":
+	case "json":
+		json = true
+	default:
+		return fmt.Errorf("LOG_FORMAT=%v: want text or json", s)
+	}
+	slog.SetDefault(New(os.Stderr, level, json))
+	return nil
+}
+
+// Fatal logs msg at the error level and exits.
+func Fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
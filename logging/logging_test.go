@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewRedacts(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	secretHex := hex.EncodeToString(secret)
+
+	for _, tt := range []struct {
+		name string
+		log  func(l *slog.Logger)
+		// want are in the output, hidden are not.
+		want   []string
+		hidden []string
+	}{
+		{
+			name:   "snake case",
+			log:    func(l *slog.Logger) { l.Info("m", "swapper_key", secret, "api_key", "k1") },
+			want:   []string{redacted},
+			hidden: []string{secretHex, string(secret), "k1"},
+		},
+		{
+			name:   "camel case",
+			log:    func(l *slog.Logger) { l.Info("m", "swapperKey", secret, "apiKey", "k1", "Preimage", secret) },
+			want:   []string{redacted},
+			hidden: []string{secretHex, string(secret), "k1"},
+		},
+		{
+			name:   "string value",
+			log:    func(l *slog.Logger) { l.Info("m", "password", "hunter2") },
+			want:   []string{redacted},
+			hidden: []string{"hunter2"},
+		},
+		{
+			name:   "group attribute",
+			log:    func(l *slog.Logger) { l.Info("m", slog.Group("swap", "preimage", secret, "hash", []byte{0xab, 0xcd})) },
+			want:   []string{redacted, "abcd"},
+			hidden: []string{secretHex, string(secret)},
+		},
+		{
+			name:   "logger group",
+			log:    func(l *slog.Logger) { l.WithGroup("swap").With("service_key", secret).Info("m") },
+			want:   []string{redacted},
+			hidden: []string{secretHex, string(secret)},
+		},
+		{
+			name: "bytes in hex",
+			log:  func(l *slog.Logger) { l.Info("m", "hash", []byte{0x01, 0xff}) },
+			want: []string{"01ff"},
+		},
+	} {
+		for _, json := range []bool{true, false} {
+			name := tt.name + " text"
+			if json {
+				name = tt.name + " json"
+			}
+			t.Run(name, func(t *testing.T) {
+				var buf bytes.Buffer
+				tt.log(New(&buf, slog.LevelInfo, json))
+				out := buf.String()
+				for _, s := range tt.want {
+					if !strings.Contains(out, s) {
+						t.Errorf("%q not in %q", s, out)
+					}
+				}
+				for _, s := range tt.hidden {
+					if strings.Contains(out, s) {
+						t.Errorf("%q in %q", s, out)
+					}
+				}
+			})
+		}
+	}
+}
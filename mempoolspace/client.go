@@ -2,111 +2,523 @@ package mempoolspace
 
 import (
 	"bytes"
-	"encoding/binary"
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
+	"swapper/chain"
+	"time"
 
-	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	defaultTimeout = 30 * time.Second
+)
+
+var tracer = otel.Tracer("swapper/mempoolspace")
+
+// RetryPolicy controls how failed GET requests are retried. Requests are
+// retried on transport errors, 5xx and 429 responses, waiting a random
+// duration up to BaseDelay*2^attempt, capped at MaxDelay. A Retry-After
+// header on a 429 response overrides the computed delay, within MaxDelay
+// too.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of clients created by NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   250 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
 type Client struct {
 	// meempool parameters
-	baseUrl string // need to implement
+	baseUrl    string
+	httpClient *http.Client
+	retry      RetryPolicy
+	observer   Observer
+	feeTarget  FeeTarget
+}
+
+// FeeTarget selects the rate of the /v1/fees/recommended response returned
+// by RecommendedFee.
+type FeeTarget string
+
+const (
+	MinimumFee  FeeTarget = "minimum"
+	EconomyFee  FeeTarget = "economy"
+	HourFee     FeeTarget = "hour"
+	HalfHourFee FeeTarget = "halfhour"
+	FastestFee  FeeTarget = "fastest"
+)
+
+// Rate returns the rate of fees selected by t.
+func (t FeeTarget) Rate(fees *RecommendedFees) (uint64, error) {
+	switch t {
+	case MinimumFee:
+		return fees.MinimumFee, nil
+	case EconomyFee:
+		return fees.EconomyFee, nil
+	case HourFee:
+		return fees.HourFee, nil
+	case HalfHourFee:
+		return fees.HalfHourFee, nil
+	case FastestFee:
+		return fees.FastestFee, nil
+	}
+	return 0, fmt.Errorf("mempoolspace: unknown fee target %q", string(t))
+}
+
+// Observer is called after each HTTP request with its method, endpoint (the
+// path with txids, addresses and numbers replaced by ":param"), duration and
+// error, if any.
+type Observer func(method, endpoint string, d time.Duration, err error)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient makes the client send its requests with httpClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(retry RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = retry
+	}
+}
+
+// WithFeeTarget makes RecommendedFee return the target rate instead of
+// MinimumFee.
+func WithFeeTarget(target FeeTarget) Option {
+	return func(c *Client) {
+		c.feeTarget = target
+	}
+}
+
+// WithObserver makes the client report its requests to observer.
+func WithObserver(observer Observer) Option {
+	return func(c *Client) {
+		c.observer = observer
+	}
+}
+
+// NewClient returns a client for the Esplora API at baseUrl, e.g.
+// https://mempool.space/api.
+func NewClient(baseUrl string, opts ...Option) *Client {
+	c := &Client{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		retry:      DefaultRetryPolicy,
+		feeTarget:  MinimumFee,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Utxo is an unspent output returned by GetUtxos.
+type Utxo = chain.Utxo
+
+// Client implements chain.Backend, chain.SpendFinder, chain.BlockHasher,
+// chain.MempoolReader and chain.UnconfirmedUtxoReader.
+var (
+	_ chain.Backend               = (*Client)(nil)
+	_ chain.SpendFinder           = (*Client)(nil)
+	_ chain.BlockHasher           = (*Client)(nil)
+	_ chain.MempoolReader         = (*Client)(nil)
+	_ chain.UnconfirmedUtxoReader = (*Client)(nil)
+)
+
+// AddressUtxo is an element of the /address/:address/utxo response.
+type AddressUtxo struct {
+	Txid   string   `json:"txid"`
+	Vout   uint32   `json:"vout"`
+	Status TxStatus `json:"status"`
+	Value  int64    `json:"value"`
+}
+
+// TxStatus is the confirmation status of a transaction.
+type TxStatus struct {
+	Confirmed   bool   `json:"confirmed"`
+	BlockHeight int32  `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+	BlockTime   uint64 `json:"block_time"`
 }
-type Utxo struct {
-	Value       btcutil.Amount
-	BlockHeight int32
-	wire.OutPoint
+
+// RecommendedFees is the /v1/fees/recommended response, in sat/vbyte.
+type RecommendedFees struct {
+	FastestFee  uint64 `json:"fastestFee"`
+	HalfHourFee uint64 `json:"halfHourFee"`
+	HourFee     uint64 `json:"hourFee"`
+	EconomyFee  uint64 `json:"economyFee"`
+	MinimumFee  uint64 `json:"minimumFee"`
 }
-type respUtxo struct {
-	txid   []byte
-	vout   int
-	status status
-	value  btcutil.Amount
+
+// StatusError is returned when the API answers with a 4xx or 5xx status.
+type StatusError struct {
+	StatusCode int
+	Message    string
+
+	retryAfter time.Duration
 }
-type status struct {
-	confirmed    bool
-	block_height int32
-	block_hash   []byte
-	block_time   uint64
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("mempoolspace: http status %v: %v", e.StatusCode, e.Message)
 }
-type RecommendedFeesResponse struct {
-	minimumFee uint64
+
+// ClientError reports whether the request itself was rejected (4xx), as
+// opposed to a failure of the server (5xx).
+func (e *StatusError) ClientError() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
 }
-type BroadcastTransactionResponse struct {
-	txid []byte
+
+// retryable reports whether a GET that failed with err may succeed if sent
+// again.
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	// Transport errors, but not our own cancellation.
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
 }
 
-func (c *Client) RecommendedFee() (uint64, error) {
-	response, err := http.Get(c.baseUrl + "/v1/fees/recommended")
-	responseBody, err := ioutil.ReadAll(response.Body)
+// backoff returns how long to wait before the attempt following attempt
+// (starting at 0).
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.retryAfter > 0 {
+		if p.MaxDelay > 0 && statusErr.retryAfter > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return statusErr.retryAfter
+	}
+	d := p.BaseDelay << uint(attempt)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// get fetches path and returns the response body, or a *StatusError if the
+// response status is not 2xx. Failed requests are retried according to the
+// client retry policy.
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var body []byte
+		body, err = c.do(ctx, http.MethodGet, path, "", nil)
+		if err == nil {
+			return body, nil
+		}
+		if attempt+1 >= c.retry.MaxAttempts || !retryable(err) {
+			return nil, err
+		}
+
+		t := time.NewTimer(c.retry.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// do sends a request within a span of its own. The trace context isn't
+// propagated to the server.
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader) (b []byte, err error) {
+	ep := endpoint(path)
+	ctx, span := tracer.Start(ctx, "mempoolspace "+method+" "+ep,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", method),
+			attribute.String("http.url", c.baseUrl+ep),
+		))
+	start := time.Now()
+	defer func() {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			span.SetAttributes(attribute.Int("http.status_code", statusErr.StatusCode))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if c.observer != nil {
+			c.observer(method, ep, time.Since(start), err)
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	response, err := c.httpClient.Do(req)
 	if err != nil {
-		fmt.Print(err.Error())
+		return nil, err
 	}
+	return readResponse(response)
+}
+
+// endpoint replaces the parameters in path, the segments made of digits or
+// longer than 20 characters, with ":param".
+func endpoint(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if len(s) > 20 {
+			segments[i] = ":param"
+			continue
+		}
+		if _, err := strconv.ParseUint(s, 10, 64); err == nil {
+			segments[i] = ":param"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func readResponse(response *http.Response) ([]byte, error) {
 	defer response.Body.Close()
-	var recommendedFeesResponse RecommendedFeesResponse
-	json.Unmarshal(responseBody, &recommendedFeesResponse)
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		statusErr := &StatusError{
+			StatusCode: response.StatusCode,
+			Message:    strings.TrimSpace(string(body)),
+		}
+		if secs, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && secs > 0 {
+			statusErr.retryAfter = time.Duration(secs) * time.Second
+		}
+		return nil, statusErr
+	}
+	return body, nil
+}
 
-	return recommendedFeesResponse.minimumFee, err
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}) error {
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("mempoolspace: decoding %v: %w", path, err)
+	}
+	return nil
+}
+
+// RecommendedFees returns the currently recommended fee rates.
+func (c *Client) RecommendedFees(ctx context.Context) (*RecommendedFees, error) {
+	var fees RecommendedFees
+	if err := c.getJSON(ctx, "/v1/fees/recommended", &fees); err != nil {
+		return nil, err
+	}
+	return &fees, nil
 }
-func (c *Client) GetUtxos(hash []byte) ([]Utxo, error) {
-	response, err := http.Get(c.baseUrl + "/address/" + hex.EncodeToString(hash) + "/utxo")
+
+// RecommendedFee returns the recommended fee rate, in sat/vbyte, of the fee
+// target of the client, MinimumFee by default.
+func (c *Client) RecommendedFee(ctx context.Context) (uint64, error) {
+	fees, err := c.RecommendedFees(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.feeTarget.Rate(fees)
+}
+
+// GetUtxos returns the confirmed utxos of address.
+func (c *Client) GetUtxos(ctx context.Context, address btcutil.Address) ([]Utxo, error) {
+	return c.addressUtxos(ctx, address, true)
+}
+
+// GetUnconfirmedUtxos implements chain.UnconfirmedUtxoReader.
+func (c *Client) GetUnconfirmedUtxos(ctx context.Context, address btcutil.Address) ([]Utxo, error) {
+	return c.addressUtxos(ctx, address, false)
+}
+
+// addressUtxos returns the utxos of address, either confirmed or only in the
+// mempool.
+func (c *Client) addressUtxos(ctx context.Context, address btcutil.Address, confirmed bool) ([]Utxo, error) {
+	var addressUtxos []AddressUtxo
+	err := c.getJSON(ctx, "/address/"+address.EncodeAddress()+"/utxo", &addressUtxos)
 	if err != nil {
 		return nil, err
 	}
-	responseBody, err := ioutil.ReadAll(response.Body)
-	defer response.Body.Close()
-	var respUtxo []respUtxo
-	json.Unmarshal(responseBody, &respUtxo)
+
 	var txos []Utxo
-	outPoints := make(map[string]struct{})
-	for i, d := range respUtxo {
-		if d.status.confirmed == true {
-			resp, err := http.Get(c.baseUrl + "/tx/" + hex.EncodeToString(d.txid) + "/hex")
-			if err != nil {
-				return nil, err
-			}
-			txHash, _ := ioutil.ReadAll(resp.Body)
-			op := wire.NewOutPoint(&txHash, uint32(i))
-			txos = append(txos, Utxo{
-				Value:       d.value,
-				BlockHeight: d.status.block_height,
-				OutPoint:    *op,
-			})
-			outPoints[op.String()] = struct{}{}
+	for _, d := range addressUtxos {
+		if d.Status.Confirmed != confirmed {
+			continue
+		}
+		txHash, err := chainhash.NewHashFromStr(d.Txid)
+		if err != nil {
+			return nil, fmt.Errorf("mempoolspace: txid %v: %w", d.Txid, err)
 		}
+		txos = append(txos, Utxo{
+			Value:       btcutil.Amount(d.Value),
+			BlockHeight: d.Status.BlockHeight,
+			OutPoint:    *wire.NewOutPoint(txHash, d.Vout),
+		})
 	}
 	return txos, nil
 }
-func (c *Client) BroadcastTransaction(redeemTx *wire.MsgTx) ([]byte, error) {
+
+// BroadcastTransaction broadcasts tx and returns its txid. It is not retried.
+func (c *Client) BroadcastTransaction(ctx context.Context, tx *wire.MsgTx) (*chainhash.Hash, error) {
 	// Serialize the transaction.
 	var buf bytes.Buffer
-	err := redeemTx.Serialize(&buf)
+	err := tx.Serialize(&buf)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.Post(c.baseUrl+"/tx", "application/json", &buf)
+	body, err := c.do(ctx, http.MethodPost, "/tx", "text/plain",
+		strings.NewReader(hex.EncodeToString(buf.Bytes())))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	txid, err := chainhash.NewHashFromStr(strings.TrimSpace(string(body)))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("mempoolspace: broadcast txid %q: %w", body, err)
 	}
-	return body, nil
+	return txid, nil
 }
-func (c *Client) CurrentHeight() (uint32, error) {
-	response, err := http.Get(c.baseUrl + "/blocks/tip/height")
+
+// CurrentHeight returns the height of the chain tip.
+func (c *Client) CurrentHeight(ctx context.Context) (uint32, error) {
+	body, err := c.get(ctx, "/blocks/tip/height")
 	if err != nil {
 		return 0, err
 	}
-	responseBody, _ := ioutil.ReadAll(response.Body)
-	defer response.Body.Close()
+	height, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("mempoolspace: tip height %q: %w", body, err)
+	}
+	return uint32(height), nil
+}
 
-	return binary.BigEndian.Uint32(responseBody), err
+// Outspend is the /tx/:txid/outspend/:vout response.
+type Outspend struct {
+	Spent  bool     `json:"spent"`
+	Txid   string   `json:"txid"`
+	Vin    uint32   `json:"vin"`
+	Status TxStatus `json:"status"`
+}
+
+// GetSpend implements chain.SpendFinder.
+func (c *Client) GetSpend(ctx context.Context, op wire.OutPoint) (*chain.Spend, error) {
+	var outspend Outspend
+	err := c.getJSON(ctx, fmt.Sprintf("/tx/%v/outspend/%v", op.Hash, op.Index), &outspend)
+	if err != nil {
+		return nil, err
+	}
+	if !outspend.Spent {
+		return nil, nil
+	}
+
+	body, err := c.get(ctx, "/tx/"+outspend.Txid+"/hex")
+	if err != nil {
+		return nil, err
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("mempoolspace: tx %v: %w", outspend.Txid, err)
+	}
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return nil, fmt.Errorf("mempoolspace: tx %v: %w", outspend.Txid, err)
+	}
+	if int(outspend.Vin) >= len(tx.TxIn) {
+		return nil, fmt.Errorf("mempoolspace: tx %v has no input %v", outspend.Txid, outspend.Vin)
+	}
+
+	spend := &chain.Spend{
+		Tx:          &tx,
+		InputIndex:  outspend.Vin,
+		Confirmed:   outspend.Status.Confirmed,
+		BlockHeight: outspend.Status.BlockHeight,
+	}
+	if outspend.Status.Confirmed {
+		blockHash, err := chainhash.NewHashFromStr(outspend.Status.BlockHash)
+		if err != nil {
+			return nil, fmt.Errorf("mempoolspace: tx %v block hash: %w", outspend.Txid, err)
+		}
+		spend.BlockHash = *blockHash
+	}
+	return spend, nil
+}
+
+// BlockHash implements chain.BlockHasher.
+func (c *Client) BlockHash(ctx context.Context, height int32) (*chainhash.Hash, error) {
+	body, err := c.get(ctx, fmt.Sprintf("/block-height/%v", height))
+	if err != nil {
+		return nil, err
+	}
+	hash, err := chainhash.NewHashFromStr(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("mempoolspace: block hash %q: %w", body, err)
+	}
+	return hash, nil
+}
+
+// GetMempoolTx implements chain.MempoolReader.
+func (c *Client) GetMempoolTx(ctx context.Context, txid chainhash.Hash) (*chain.MempoolTx, error) {
+	var tx Transaction
+	if err := c.getJSON(ctx, "/tx/"+txid.String(), &tx); err != nil {
+		return nil, err
+	}
+	if tx.Status.Confirmed {
+		return nil, nil
+	}
+
+	mempoolTx := &chain.MempoolTx{
+		Fee:   btcutil.Amount(tx.Fee),
+		VSize: (tx.Weight + 3) / 4,
+	}
+	parents := make(map[string]struct{})
+	for _, in := range tx.Vin {
+		if in.Sequence < wire.MaxTxInSequenceNum-1 {
+			mempoolTx.Replaceable = true
+		}
+		parents[in.Txid] = struct{}{}
+	}
+	for parent := range parents {
+		var status TxStatus
+		if err := c.getJSON(ctx, "/tx/"+parent+"/status", &status); err != nil {
+			return nil, err
+		}
+		if !status.Confirmed {
+			mempoolTx.UnconfirmedParents++
+		}
+	}
+	return mempoolTx, nil
 }
@@ -0,0 +1,209 @@
+package mempoolspace
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// testRetry retries fast enough for the tests.
+var testRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// testServer serves the bodies of routes, by path, and 404 otherwise.
+func testServer(t *testing.T, routes map[string]string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(server.URL+"/", WithRetryPolicy(testRetry))
+}
+
+func TestClientDecoding(t *testing.T) {
+	ctx := context.Background()
+	address, err := btcutil.NewAddressWitnessScriptHash(make([]byte, 32), &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := chainhash.Hash{1}
+	parent := chainhash.Hash{2}
+	blockHash := chainhash.Hash{3}
+
+	spendTx := wire.NewMsgTx(2)
+	spendTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&txid, 0), nil, nil))
+	spendTx.AddTxOut(&wire.TxOut{Value: 900})
+	var buf bytes.Buffer
+	if err := spendTx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	spendTxid := spendTx.TxHash()
+
+	c := testServer(t, map[string]string{
+		"/v1/fees/recommended": `{"fastestFee":20,"halfHourFee":15,"hourFee":10,"economyFee":5,"minimumFee":1}`,
+		"/address/" + address.EncodeAddress() + "/utxo": fmt.Sprintf(`[
+			{"txid":"%v","vout":1,"value":1000,"status":{"confirmed":true,"block_height":100,"block_hash":"%v"}},
+			{"txid":"%v","vout":0,"value":2000,"status":{"confirmed":false}}]`, txid, blockHash, parent),
+		"/blocks/tip/height": "101\n",
+		"/block-height/100":  blockHash.String(),
+		"/tx/" + txid.String(): fmt.Sprintf(`{"txid":"%v","weight":561,"fee":300,"status":{"confirmed":false},
+			"vin":[{"txid":"%v","vout":0,"sequence":4294967293}]}`, txid, parent),
+		"/tx/" + parent.String() + "/status": `{"confirmed":false}`,
+		"/tx/" + txid.String() + "/outspend/0": fmt.Sprintf(`{"spent":true,"txid":"%v","vin":0,
+			"status":{"confirmed":true,"block_height":101,"block_hash":"%v"}}`, spendTxid, blockHash),
+		"/tx/" + spendTxid.String() + "/hex": hex.EncodeToString(buf.Bytes()),
+	})
+
+	fee, err := c.RecommendedFee(ctx)
+	if err != nil || fee != 1 {
+		t.Errorf("RecommendedFee() = %v, %v, want the minimum fee 1", fee, err)
+	}
+	WithFeeTarget(HourFee)(c)
+	if fee, err := c.RecommendedFee(ctx); err != nil || fee != 10 {
+		t.Errorf("RecommendedFee() = %v, %v, want the hour fee 10", fee, err)
+	}
+
+	utxos, err := c.GetUtxos(ctx, address)
+	if err != nil {
+		t.Fatalf("GetUtxos() error: %v", err)
+	}
+	want := Utxo{Value: 1000, BlockHeight: 100, OutPoint: *wire.NewOutPoint(&txid, 1)}
+	if len(utxos) != 1 || utxos[0] != want {
+		t.Errorf("GetUtxos() = %v, want [%v]", utxos, want)
+	}
+	unconfirmed, err := c.GetUnconfirmedUtxos(ctx, address)
+	if err != nil {
+		t.Fatalf("GetUnconfirmedUtxos() error: %v", err)
+	}
+	want = Utxo{Value: 2000, OutPoint: *wire.NewOutPoint(&parent, 0)}
+	if len(unconfirmed) != 1 || unconfirmed[0] != want {
+		t.Errorf("GetUnconfirmedUtxos() = %v, want [%v]", unconfirmed, want)
+	}
+
+	if height, err := c.CurrentHeight(ctx); err != nil || height != 101 {
+		t.Errorf("CurrentHeight() = %v, %v, want 101", height, err)
+	}
+	if hash, err := c.BlockHash(ctx, 100); err != nil || *hash != blockHash {
+		t.Errorf("BlockHash() = %v, %v, want %v", hash, err, blockHash)
+	}
+
+	mempoolTx, err := c.GetMempoolTx(ctx, txid)
+	if err != nil {
+		t.Fatalf("GetMempoolTx() error: %v", err)
+	}
+	if mempoolTx.Fee != 300 || mempoolTx.VSize != 141 || !mempoolTx.Replaceable || mempoolTx.UnconfirmedParents != 1 {
+		t.Errorf("GetMempoolTx() = %+v", mempoolTx)
+	}
+
+	spend, err := c.GetSpend(ctx, *wire.NewOutPoint(&txid, 0))
+	if err != nil {
+		t.Fatalf("GetSpend() error: %v", err)
+	}
+	if spend.Tx.TxHash() != spendTxid || !spend.Confirmed || spend.BlockHeight != 101 || spend.BlockHash != blockHash {
+		t.Errorf("GetSpend() = %+v", spend)
+	}
+
+	// An unknown route is a client error, not retried.
+	_, err = c.GetSpend(ctx, *wire.NewOutPoint(&parent, 5))
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || !statusErr.ClientError() {
+		t.Errorf("GetSpend() of an unknown outpoint error = %v, want a client StatusError", err)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		// statuses are answered in turn, then 200.
+		statuses     []int
+		retryAfter   string
+		wantRequests int
+		wantStatus   int
+	}{
+		{name: "success", wantRequests: 1},
+		{name: "server errors then success", statuses: []int{503, 500}, wantRequests: 3},
+		{name: "too many server errors", statuses: []int{503, 503, 503}, wantRequests: 3, wantStatus: 503},
+		{name: "client error not retried", statuses: []int{400}, wantRequests: 1, wantStatus: 400},
+		// Retry-After is capped at MaxDelay, or the test would wait 60s.
+		{name: "rate limited", statuses: []int{429}, retryAfter: "60", wantRequests: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= len(tt.statuses) {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					http.Error(w, "failure", tt.statuses[requests-1])
+					return
+				}
+				fmt.Fprint(w, "100")
+			}))
+			defer server.Close()
+			c := NewClient(server.URL, WithRetryPolicy(testRetry))
+
+			start := time.Now()
+			height, err := c.CurrentHeight(context.Background())
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("CurrentHeight() took %v", d)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("%v requests, want %v", requests, tt.wantRequests)
+			}
+			if tt.wantStatus == 0 {
+				if err != nil || height != 100 {
+					t.Errorf("CurrentHeight() = %v, %v, want 100", height, err)
+				}
+				return
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+				t.Errorf("CurrentHeight() error = %v, want status %v", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 4, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for _, tt := range []struct {
+		name    string
+		attempt int
+		err     error
+		max     time.Duration
+		exact   bool
+	}{
+		{name: "first attempt", attempt: 0, err: errors.New("down"), max: 100 * time.Millisecond},
+		{name: "exponential", attempt: 2, err: errors.New("down"), max: 400 * time.Millisecond},
+		{name: "capped", attempt: 10, err: errors.New("down"), max: time.Second},
+		{name: "retry after", err: &StatusError{StatusCode: 429, retryAfter: 500 * time.Millisecond},
+			max: 500 * time.Millisecond, exact: true},
+		{name: "retry after capped", err: &StatusError{StatusCode: 429, retryAfter: time.Minute},
+			max: time.Second, exact: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				d := p.backoff(tt.attempt, tt.err)
+				if d < 0 || d > tt.max || tt.exact && d != tt.max {
+					t.Fatalf("backoff() = %v, want up to %v (exact %v)", d, tt.max, tt.exact)
+				}
+			}
+		})
+	}
+}
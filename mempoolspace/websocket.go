@@ -0,0 +1,308 @@
+package mempoolspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"swapper/chain"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	pingInterval = 30 * time.Second
+	writeTimeout = 10 * time.Second
+	// readTimeout must be larger than pingInterval: the server answers each
+	// ping, so a silent connection is a dead one.
+	readTimeout = 2 * pingInterval
+)
+
+// wsBlock is a new block announced by the websocket API.
+type wsBlock struct {
+	ID                string `json:"id"`
+	Height            int32  `json:"height"`
+	Timestamp         int64  `json:"timestamp"`
+	PreviousBlockHash string `json:"previousblockhash"`
+}
+
+// Transaction is the Esplora representation of a transaction.
+type Transaction struct {
+	Txid   string     `json:"txid"`
+	Vin    []TxInput  `json:"vin"`
+	Vout   []TxOutput `json:"vout"`
+	Weight int64      `json:"weight"`
+	Fee    int64      `json:"fee"`
+	Status TxStatus   `json:"status"`
+}
+
+// TxInput is an input of a Transaction.
+type TxInput struct {
+	Txid     string `json:"txid"`
+	Vout     uint32 `json:"vout"`
+	Sequence uint32 `json:"sequence"`
+}
+
+// TxOutput is an output of a Transaction.
+type TxOutput struct {
+	ScriptPubKeyAddress string `json:"scriptpubkey_address"`
+	Value               int64  `json:"value"`
+}
+
+type addressTransactions struct {
+	Mempool   []Transaction `json:"mempool"`
+	Confirmed []Transaction `json:"confirmed"`
+}
+
+type wsMessage struct {
+	Block                     *wsBlock                       `json:"block"`
+	MultiAddressTransactions  map[string]addressTransactions `json:"multi-address-transactions"`
+	TrackAddressesErrorString string                         `json:"track-addresses-error"`
+}
+
+// Subscriber follows new blocks and the transactions of a set of addresses
+// through the mempool.space websocket API, reconnecting whenever the
+// connection drops. All the addresses are tracked on a single connection
+// with the track-addresses action, the multi address form of track-address.
+type Subscriber struct {
+	url   string
+	retry RetryPolicy
+
+	blocks   chan chain.Block
+	deposits chan chain.Deposit
+
+	mu        sync.Mutex
+	addresses map[string]struct{}
+	conn      *websocket.Conn
+}
+
+// NewSubscriber returns a subscriber for the websocket API at url, e.g.
+// wss://mempool.space/api/v1/ws. Call Run to start it.
+func NewSubscriber(url string) *Subscriber {
+	return &Subscriber{
+		url:       url,
+		retry:     DefaultRetryPolicy,
+		blocks:    make(chan chain.Block, 16),
+		deposits:  make(chan chain.Deposit, 256),
+		addresses: make(map[string]struct{}),
+	}
+}
+
+// Subscriber implements chain.Notifier.
+var _ chain.Notifier = (*Subscriber)(nil)
+
+// Blocks delivers every new block.
+func (s *Subscriber) Blocks() <-chan chain.Block {
+	return s.blocks
+}
+
+// Deposits delivers the outputs paying to tracked addresses, once when seen
+// in the mempool and again when confirmed. After a reconnection the current
+// transactions of the tracked addresses are delivered again.
+func (s *Subscriber) Deposits() <-chan chain.Deposit {
+	return s.deposits
+}
+
+// TrackAddress adds address to the tracked addresses. created is unused:
+// the Esplora API finds the outputs of any address.
+func (s *Subscriber) TrackAddress(address string, created time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.addresses[address]; ok {
+		return nil
+	}
+	s.addresses[address] = struct{}{}
+	return s.sendTrackedLocked()
+}
+
+// UntrackAddress removes address from the tracked addresses.
+func (s *Subscriber) UntrackAddress(address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.addresses[address]; !ok {
+		return nil
+	}
+	delete(s.addresses, address)
+	return s.sendTrackedLocked()
+}
+
+// sendTrackedLocked sends the tracked addresses on the current connection,
+// if any. s.mu must be held.
+func (s *Subscriber) sendTrackedLocked() error {
+	if s.conn == nil {
+		return nil
+	}
+	addresses := make([]string, 0, len(s.addresses))
+	for a := range s.addresses {
+		addresses = append(addresses, a)
+	}
+	return s.writeLocked(map[string]interface{}{"track-addresses": addresses})
+}
+
+func (s *Subscriber) writeLocked(v interface{}) error {
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return s.conn.WriteJSON(v)
+}
+
+// Run connects and delivers events until ctx is done. Connection errors are
+// logged and followed by a reconnection.
+func (s *Subscriber) Run(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// A connection that lived long enough resets the backoff.
+		if time.Since(start) > readTimeout {
+			attempt = 0
+		}
+		delay := s.retry.backoff(attempt, err)
+		slog.Warn("mempoolspace websocket disconnected", "url", s.url, "error", err, "retry_in", delay)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Subscriber) runOnce(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, s.url, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.conn = conn
+	err = s.writeLocked(map[string]interface{}{"action": "want", "data": []string{"blocks"}})
+	if err == nil {
+		err = s.sendTrackedLocked()
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+	}()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.ping(ctx, conn, done)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		// The same addresses would be refused again after a reconnection,
+		// e.g. above the server limit: keep the connection for the blocks.
+		if msg.TrackAddressesErrorString != "" {
+			s.mu.Lock()
+			n := len(s.addresses)
+			s.mu.Unlock()
+			slog.Error("mempoolspace track-addresses refused", "url", s.url, "addresses", n,
+				"error", msg.TrackAddressesErrorString)
+		}
+		if msg.Block != nil {
+			b, err := msg.Block.block()
+			if err != nil {
+				return err
+			}
+			select {
+			case s.blocks <- b:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		for address, txs := range msg.MultiAddressTransactions {
+			for _, tx := range append(txs.Mempool, txs.Confirmed...) {
+				if err := s.deliverDeposits(ctx, address, tx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// ping keeps the connection alive; closing it on ctx cancellation also
+// unblocks the reader.
+func (s *Subscriber) ping(ctx context.Context, conn *websocket.Conn, done <-chan struct{}) {
+	t := time.NewTicker(pingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			conn.Close()
+			return
+		case <-t.C:
+			s.mu.Lock()
+			err := s.writeLocked(map[string]string{"action": "ping"})
+			s.mu.Unlock()
+			if err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *Subscriber) deliverDeposits(ctx context.Context, address string, tx Transaction) error {
+	txid, err := chainhash.NewHashFromStr(tx.Txid)
+	if err != nil {
+		return fmt.Errorf("txid %v: %w", tx.Txid, err)
+	}
+	var blockHash chainhash.Hash
+	if tx.Status.Confirmed {
+		h, err := chainhash.NewHashFromStr(tx.Status.BlockHash)
+		if err != nil {
+			return fmt.Errorf("block hash %v: %w", tx.Status.BlockHash, err)
+		}
+		blockHash = *h
+	}
+	for vout, out := range tx.Vout {
+		if out.ScriptPubKeyAddress != address {
+			continue
+		}
+		d := chain.Deposit{
+			Address:     address,
+			OutPoint:    *wire.NewOutPoint(txid, uint32(vout)),
+			Value:       btcutil.Amount(out.Value),
+			Confirmed:   tx.Status.Confirmed,
+			BlockHeight: tx.Status.BlockHeight,
+			BlockHash:   blockHash,
+		}
+		select {
+		case s.deposits <- d:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *wsBlock) block() (chain.Block, error) {
+	hash, err := chainhash.NewHashFromStr(b.ID)
+	if err != nil {
+		return chain.Block{}, fmt.Errorf("block hash %v: %w", b.ID, err)
+	}
+	prevHash, err := chainhash.NewHashFromStr(b.PreviousBlockHash)
+	if err != nil {
+		return chain.Block{}, fmt.Errorf("block hash %v: %w", b.PreviousBlockHash, err)
+	}
+	return chain.Block{Hash: *hash, PrevHash: *prevHash, Height: b.Height}, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"swapper/mempoolspace"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	swapsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swapper_swaps_created_total",
+		Help: "Swaps created.",
+	}, []string{"network"})
+	swapTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swapper_swap_transitions_total",
+		Help: "Swaps reaching a state: funded, claimed, claimed by third party, refunded or expired.",
+	}, []string{"network", "state"})
+	redeemFeesPaid = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swapper_redeem_fees_satoshis_total",
+		Help: "Miner fees paid by the confirmed redeem transactions.",
+	}, []string{"network"})
+	redeemFeeSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "swapper_redeem_fee_satoshis",
+		Help:    "Miner fee of each confirmed redeem transaction.",
+		Buckets: prometheus.ExponentialBuckets(100, 2, 12),
+	}, []string{"network"})
+	mempoolRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "swapper_mempoolspace_request_duration_seconds",
+		Help:    "Duration of the mempool.space HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "endpoint"})
+	mempoolRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "swapper_mempoolspace_request_errors_total",
+		Help: "Failed mempool.space HTTP requests, by HTTP status (0 for transport errors).",
+	}, []string{"method", "endpoint", "status"})
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "swapper_db_query_duration_seconds",
+		Help:    "Duration of the database queries, by store function.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"function"})
+)
+
+// liabilitiesCollector reports the open swaps and what they owe, read from
+// the database at each scrape.
+type liabilitiesCollector struct{}
+
+var (
+	openSwapsDesc = prometheus.NewDesc("swapper_open_swaps",
+		"Swaps not in a final state.", []string{"network"}, nil)
+	committedDesc = prometheus.NewDesc("swapper_committed_satoshis",
+		"Amount of the funded swaps not yet redeemed, to be paid over Lightning.", []string{"network"}, nil)
+	depositsDesc = prometheus.NewDesc("swapper_unspent_deposits_satoshis",
+		"Value of the unspent deposits of the open swaps.", []string{"network"}, nil)
+)
+
+func (liabilitiesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openSwapsDesc
+	ch <- committedDesc
+	ch <- depositsDesc
+}
+
+func (liabilitiesCollector) Collect(ch chan<- prometheus.Metric) {
+	l, err := getLiabilities(context.Background())
+	if err != nil {
+		slog.Error("getLiabilities failed", "error", err)
+		return
+	}
+	for network, v := range l {
+		ch <- prometheus.MustNewConstMetric(openSwapsDesc, prometheus.GaugeValue, float64(v.openSwaps), network)
+		ch <- prometheus.MustNewConstMetric(committedDesc, prometheus.GaugeValue, float64(v.committed), network)
+		ch <- prometheus.MustNewConstMetric(depositsDesc, prometheus.GaugeValue, float64(v.deposits), network)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(swapsCreated, swapTransitions, redeemFeesPaid, redeemFeeSize,
+		mempoolRequestDuration, mempoolRequestErrors, dbQueryDuration, liabilitiesCollector{})
+}
+
+// serveHTTP serves /metrics and the /healthz and /readyz probes of h on
+// METRICS_ADDRESS, if set.
+func serveHTTP(h *healthChecker) {
+	address := os.Getenv("METRICS_ADDRESS")
+	if address == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", h.healthz)
+	mux.HandleFunc("/readyz", h.readyz)
+	go func() {
+		err := http.ListenAndServe(address, mux)
+		slog.Error("HTTP server stopped", "error", err)
+	}()
+}
+
+func observeSwapTransition(network string, state swapState, n int) {
+	swapTransitions.WithLabelValues(network, state.String()).Add(float64(n))
+}
+
+func observeRedeemFee(network string, fee btcutil.Amount) {
+	redeemFeesPaid.WithLabelValues(network).Add(float64(fee))
+	redeemFeeSize.WithLabelValues(network).Observe(float64(fee))
+}
+
+// observeMempoolRequest implements mempoolspace.Observer.
+func observeMempoolRequest(method, endpoint string, d time.Duration, err error) {
+	mempoolRequestDuration.WithLabelValues(method, endpoint).Observe(d.Seconds())
+	if err != nil {
+		status := "0"
+		var statusErr *mempoolspace.StatusError
+		if errors.As(err, &statusErr) {
+			status = strconv.Itoa(statusErr.StatusCode)
+		}
+		mempoolRequestErrors.WithLabelValues(method, endpoint, status).Inc()
+	}
+}
+
+func observeQuery(function string, start time.Time) {
+	dbQueryDuration.WithLabelValues(function).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"swapper/bitcoind"
+	"swapper/chain"
+	"swapper/lndchain"
+	"swapper/mempoolspace"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"google.golang.org/grpc"
+)
+
+// network is a bitcoin network served by this swapper together with the
+// chain backend used for it.
+type network struct {
+	params     *chaincfg.Params
+	backend    chain.Backend
+	subscriber chain.Notifier
+	// hasher is the backend when enough of its providers return block
+	// hashes to check the recorded confirmations for reorgs, nil otherwise.
+	hasher chain.BlockHasher
+	// lnd is the lnd provider, if any, which must be started to follow the
+	// tracked addresses even when it isn't the subscriber.
+	lnd *lndchain.Backend
+}
+
+var (
+	// networks holds the served networks keyed by chaincfg.Params.Name.
+	networks map[string]*network
+	// defaultNetwork is used by requests that don't name a network.
+	defaultNetwork *network
+)
+
+var defaultMempoolURLs = map[string]string{
+	chaincfg.MainNetParams.Name:  "https://mempool.space/api",
+	chaincfg.TestNet3Params.Name: "https://mempool.space/testnet/api",
+	chaincfg.SigNetParams.Name:   "https://mempool.space/signet/api",
+}
+
+var defaultMempoolWSURLs = map[string]string{
+	chaincfg.MainNetParams.Name:  "wss://mempool.space/api/v1/ws",
+	chaincfg.TestNet3Params.Name: "wss://mempool.space/testnet/api/v1/ws",
+	chaincfg.SigNetParams.Name:   "wss://mempool.space/signet/api/v1/ws",
+}
+
+// lndLookback is how many blocks below the tip lnd searches for the deposits
+// of a new swap address.
+const lndLookback = 2016
+
+// networkParams maps the network names accepted in NETWORKS and in RPC
+// requests to their chain parameters.
+func networkParams(name string) (*chaincfg.Params, error) {
+	switch strings.ToLower(name) {
+	case "mainnet", "bitcoin":
+		return &chaincfg.MainNetParams, nil
+	case "testnet", "testnet3":
+		return &chaincfg.TestNet3Params, nil
+	case "signet":
+		return &chaincfg.SigNetParams, nil
+	case "regtest":
+		return &chaincfg.RegressionNetParams, nil
+	}
+	return nil, fmt.Errorf("unknown network: %v", name)
+}
+
+// loadNetworks reads the comma separated NETWORKS variable (default
+// "mainnet"). The first network listed is the default one. See loadNetwork
+// for the configuration of each network. MEMPOOL_TIMEOUT sets the timeout of
+// each Esplora request and MEMPOOL_FEE_TARGET (minimum, economy, hour,
+// halfhour or fastest, default minimum) the recommended fee rate used. lnd is
+// the connection used by networks whose chain backend is lnd.
+func loadNetworks(lnd *grpc.ClientConn) error {
+	names := os.Getenv("NETWORKS")
+	if names == "" {
+		names = "mainnet"
+	}
+
+	opts := []mempoolspace.Option{mempoolspace.WithObserver(observeMempoolRequest)}
+	if t := os.Getenv("MEMPOOL_TIMEOUT"); t != "" {
+		timeout, err := time.ParseDuration(t)
+		if err != nil {
+			return fmt.Errorf("MEMPOOL_TIMEOUT=%v: %w", t, err)
+		}
+		opts = append(opts, mempoolspace.WithHTTPClient(&http.Client{Timeout: timeout}))
+	}
+	if t := os.Getenv("MEMPOOL_FEE_TARGET"); t != "" {
+		target := mempoolspace.FeeTarget(t)
+		if _, err := target.Rate(&mempoolspace.RecommendedFees{}); err != nil {
+			return fmt.Errorf("MEMPOOL_FEE_TARGET=%v: %w", t, err)
+		}
+		opts = append(opts, mempoolspace.WithFeeTarget(target))
+	}
+
+	networks = make(map[string]*network)
+	defaultNetwork = nil
+	for _, name := range strings.Split(names, ",") {
+		params, err := networkParams(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		if _, ok := networks[params.Name]; ok {
+			return fmt.Errorf("network %v listed twice", params.Name)
+		}
+
+		n, err := loadNetwork(params, opts, lnd)
+		if err != nil {
+			return fmt.Errorf("network %v: %w", params.Name, err)
+		}
+		networks[params.Name] = n
+		if defaultNetwork == nil {
+			defaultNetwork = n
+		}
+	}
+
+	return nil
+}
+
+// loadNetwork reads the chain backends of a network from variables prefixed
+// with its upper cased name, e.g. REGTEST_:
+//
+//	<NAME>_BITCOIND_HOST, _USER, _PASS   bitcoind JSON-RPC, used first
+//	<NAME>_BITCOIND_WALLET               watch-only descriptor wallet
+//	<NAME>_BITCOIND_ZMQ_BLOCK, _ZMQ_TX   bitcoind notifications, both or
+//	                                     none
+//	<NAME>_LND_CHAIN                     "true" uses the chain of lnd, which
+//	                                     must run on this network
+//	<NAME>_MEMPOOL_URL                   comma separated Esplora providers
+//	<NAME>_MEMPOOL_WS_URL                mempool.space notifications, "none"
+//	                                     disables them
+//	<NAME>_CHAIN_QUORUM                  providers that must agree on UTXO
+//	                                     sets and tip height (default 1)
+//
+// The mempool.space URLs default to the public instance unless bitcoind or lnd
+// is configured.
+func loadNetwork(params *chaincfg.Params, opts []mempoolspace.Option, lnd *grpc.ClientConn) (*network, error) {
+	prefix := strings.ToUpper(params.Name)
+	n := &network{params: params}
+
+	var providers []chain.Backend
+	var node *bitcoind.Backend
+	zmqBlock, zmqTx := os.Getenv(prefix+"_BITCOIND_ZMQ_BLOCK"), os.Getenv(prefix+"_BITCOIND_ZMQ_TX")
+	if (zmqBlock == "") != (zmqTx == "") {
+		return nil, fmt.Errorf("%v_BITCOIND_ZMQ_BLOCK and %v_BITCOIND_ZMQ_TX must be set together", prefix, prefix)
+	}
+	if host := os.Getenv(prefix + "_BITCOIND_HOST"); host != "" {
+		var err error
+		node, err = bitcoind.New(bitcoind.Config{
+			Host:     host,
+			User:     os.Getenv(prefix + "_BITCOIND_USER"),
+			Pass:     os.Getenv(prefix + "_BITCOIND_PASS"),
+			Wallet:   os.Getenv(prefix + "_BITCOIND_WALLET"),
+			ZMQBlock: zmqBlock,
+			ZMQTx:    zmqTx,
+			Params:   params,
+		})
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, node)
+	}
+
+	var lndBackend *lndchain.Backend
+	if v := os.Getenv(prefix + "_LND_CHAIN"); v != "" {
+		useLnd, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v_LND_CHAIN=%v: %w", prefix, v, err)
+		}
+		if useLnd {
+			lndBackend = lndchain.New(lnd, params, lndLookback)
+			providers = append(providers, lndBackend)
+			n.lnd = lndBackend
+		}
+	}
+
+	urls := os.Getenv(prefix + "_MEMPOOL_URL")
+	if urls == "" && node == nil && lndBackend == nil {
+		urls = defaultMempoolURLs[params.Name]
+	}
+	if urls != "" {
+		for _, url := range strings.Split(urls, ",") {
+			providers = append(providers, mempoolspace.NewClient(strings.TrimSpace(url), opts...))
+		}
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("none of %v_BITCOIND_HOST, %v_LND_CHAIN and %v_MEMPOOL_URL is set", prefix, prefix, prefix)
+	}
+
+	quorum := 1
+	if q := os.Getenv(prefix + "_CHAIN_QUORUM"); q != "" {
+		var err error
+		quorum, err = strconv.Atoi(q)
+		if err != nil {
+			return nil, fmt.Errorf("%v_CHAIN_QUORUM=%v: %w", prefix, q, err)
+		}
+	}
+	backend, err := chain.NewMulti(quorum, providers...)
+	if err != nil {
+		return nil, err
+	}
+	n.backend = backend
+	if backend.HashesBlocks() {
+		n.hasher = backend
+	} else {
+		slog.Warn("too few chain providers return block hashes, reorgs of recorded confirmations won't be detected",
+			"network", params.Name, "quorum", quorum)
+	}
+
+	wsURL := os.Getenv(prefix + "_MEMPOOL_WS_URL")
+	if wsURL == "" && node == nil && lndBackend == nil {
+		wsURL = defaultMempoolWSURLs[params.Name]
+	}
+	switch {
+	case node != nil && zmqBlock != "":
+		n.subscriber = node
+	case lndBackend != nil:
+		n.subscriber = lndBackend
+	case wsURL != "" && wsURL != "none":
+		n.subscriber = mempoolspace.NewSubscriber(wsURL)
+	}
+
+	return n, nil
+}
+
+// trackers returns what must be told about the addresses of the swaps of n:
+// the chain backend, which tells the providers needing it, and the
+// subscriber. A provider which is also the subscriber is told twice, which
+// is harmless.
+func (n *network) trackers() []chain.AddressTracker {
+	var trackers []chain.AddressTracker
+	if t, ok := n.backend.(chain.AddressTracker); ok {
+		trackers = append(trackers, t)
+	}
+	if n.subscriber != nil {
+		trackers = append(trackers, n.subscriber)
+	}
+	return trackers
+}
+
+// getNetwork returns the served network with the given name, or the default
+// network if name is empty.
+func getNetwork(name string) (*network, error) {
+	if name == "" {
+		return defaultNetwork, nil
+	}
+	params, err := networkParams(name)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := networks[params.Name]
+	if !ok {
+		return nil, fmt.Errorf("network %v is not served", params.Name)
+	}
+	return n, nil
+}
+
+// chainClient returns the chain backend of the network net.
+func chainClient(net *chaincfg.Params) (chain.Backend, error) {
+	n, ok := networks[net.Name]
+	if !ok {
+		return nil, fmt.Errorf("network %v is not served", net.Name)
+	}
+	return n.backend, nil
+}
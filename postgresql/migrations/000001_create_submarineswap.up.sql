@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS submarineswap (
+	netID smallint NOT NULL,
+	hash bytea NOT NULL,
+	lockHeight bigint NOT NULL,
+	swapperKey bytea NOT NULL,
+	script bytea NOT NULL,
+	PRIMARY KEY (hash)
+);
@@ -0,0 +1,2 @@
+DROP INDEX submarineswap_probinghash_idx;
+ALTER TABLE submarineswap DROP COLUMN probingHash;
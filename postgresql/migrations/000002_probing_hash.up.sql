@@ -0,0 +1,4 @@
+ALTER TABLE submarineswap ADD COLUMN probingHash bytea;
+UPDATE submarineswap SET probingHash = sha256('probing-01:' || hash);
+ALTER TABLE submarineswap ALTER COLUMN probingHash SET NOT NULL;
+CREATE UNIQUE INDEX submarineswap_probinghash_idx ON submarineswap (probingHash);
@@ -0,0 +1,5 @@
+DROP INDEX submarineswap_network_probinghash_idx;
+CREATE UNIQUE INDEX submarineswap_probinghash_idx ON submarineswap (probingHash);
+ALTER TABLE submarineswap DROP CONSTRAINT submarineswap_pkey;
+ALTER TABLE submarineswap ADD PRIMARY KEY (hash);
+ALTER TABLE submarineswap DROP COLUMN network;
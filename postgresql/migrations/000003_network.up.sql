@@ -0,0 +1,7 @@
+ALTER TABLE submarineswap ADD COLUMN network text;
+UPDATE submarineswap SET network = CASE netID WHEN 5 THEN 'mainnet' ELSE 'testnet3' END;
+ALTER TABLE submarineswap ALTER COLUMN network SET NOT NULL;
+ALTER TABLE submarineswap DROP CONSTRAINT submarineswap_pkey;
+ALTER TABLE submarineswap ADD PRIMARY KEY (network, hash);
+DROP INDEX submarineswap_probinghash_idx;
+CREATE UNIQUE INDEX submarineswap_network_probinghash_idx ON submarineswap (network, probingHash);
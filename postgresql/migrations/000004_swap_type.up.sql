@@ -0,0 +1 @@
+ALTER TABLE submarineswap ADD COLUMN swapType smallint NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+DROP TABLE swapdeposit;
+DROP INDEX submarineswap_network_state_idx;
+DROP INDEX submarineswap_network_address_idx;
+ALTER TABLE submarineswap DROP COLUMN redeemTxid;
+ALTER TABLE submarineswap DROP COLUMN preimage;
+ALTER TABLE submarineswap DROP COLUMN state;
+ALTER TABLE submarineswap DROP COLUMN address;
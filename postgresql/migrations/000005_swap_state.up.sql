@@ -0,0 +1,19 @@
+ALTER TABLE submarineswap ADD COLUMN address text;
+ALTER TABLE submarineswap ADD COLUMN state smallint NOT NULL DEFAULT 0;
+ALTER TABLE submarineswap ADD COLUMN preimage bytea;
+ALTER TABLE submarineswap ADD COLUMN redeemTxid bytea;
+CREATE INDEX submarineswap_network_address_idx ON submarineswap (network, address);
+CREATE INDEX submarineswap_network_state_idx ON submarineswap (network, state);
+CREATE TABLE IF NOT EXISTS swapdeposit (
+	network text NOT NULL,
+	hash bytea NOT NULL,
+	txid bytea NOT NULL,
+	vout bigint NOT NULL,
+	value bigint NOT NULL,
+	blockHeight bigint NOT NULL DEFAULT 0,
+	spendTxid bytea,
+	spendPath smallint,
+	PRIMARY KEY (network, txid, vout),
+	FOREIGN KEY (network, hash) REFERENCES submarineswap (network, hash)
+);
+CREATE INDEX swapdeposit_network_hash_idx ON swapdeposit (network, hash);
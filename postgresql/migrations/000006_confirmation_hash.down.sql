@@ -0,0 +1,3 @@
+ALTER TABLE swapdeposit DROP COLUMN spendBlockHash;
+ALTER TABLE swapdeposit DROP COLUMN spendBlockHeight;
+ALTER TABLE swapdeposit DROP COLUMN blockHash;
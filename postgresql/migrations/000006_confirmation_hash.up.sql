@@ -0,0 +1,3 @@
+ALTER TABLE swapdeposit ADD COLUMN blockHash bytea;
+ALTER TABLE swapdeposit ADD COLUMN spendBlockHeight bigint;
+ALTER TABLE swapdeposit ADD COLUMN spendBlockHash bytea;
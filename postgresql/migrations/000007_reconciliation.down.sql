@@ -0,0 +1,7 @@
+ALTER TABLE submarineswap DROP COLUMN excess;
+ALTER TABLE submarineswap DROP COLUMN redeemFee;
+ALTER TABLE submarineswap DROP COLUMN depositTotal;
+ALTER TABLE submarineswap DROP COLUMN depositCount;
+ALTER TABLE submarineswap DROP COLUMN reconciliation;
+ALTER TABLE submarineswap DROP COLUMN refundAddress;
+ALTER TABLE submarineswap DROP COLUMN amount;
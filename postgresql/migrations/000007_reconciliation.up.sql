@@ -0,0 +1,7 @@
+ALTER TABLE submarineswap ADD COLUMN amount bigint NOT NULL DEFAULT 0;
+ALTER TABLE submarineswap ADD COLUMN refundAddress text;
+ALTER TABLE submarineswap ADD COLUMN reconciliation smallint NOT NULL DEFAULT 0;
+ALTER TABLE submarineswap ADD COLUMN depositCount integer NOT NULL DEFAULT 0;
+ALTER TABLE submarineswap ADD COLUMN depositTotal bigint NOT NULL DEFAULT 0;
+ALTER TABLE submarineswap ADD COLUMN redeemFee bigint NOT NULL DEFAULT 0;
+ALTER TABLE submarineswap ADD COLUMN excess bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE submarineswap DROP COLUMN feeRate;
+ALTER TABLE submarineswap DROP COLUMN serviceFee;
+ALTER TABLE submarineswap DROP COLUMN quoteID;
+DROP TABLE swapquote;
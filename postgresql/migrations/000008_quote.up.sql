@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS swapquote (
+	id bytea NOT NULL,
+	network text NOT NULL,
+	amount bigint NOT NULL,
+	serviceFee bigint NOT NULL,
+	feeRate bigint NOT NULL,
+	minerFee bigint NOT NULL,
+	expiry timestamptz NOT NULL,
+	hash bytea,
+	PRIMARY KEY (id)
+);
+ALTER TABLE submarineswap ADD COLUMN quoteID bytea;
+ALTER TABLE submarineswap ADD COLUMN serviceFee bigint NOT NULL DEFAULT 0;
+ALTER TABLE submarineswap ADD COLUMN feeRate bigint NOT NULL DEFAULT 0;
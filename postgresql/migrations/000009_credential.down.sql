@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS submarineswap_credentialID_idx;
+ALTER TABLE submarineswap DROP COLUMN credentialID;
+DROP TABLE apicredential;
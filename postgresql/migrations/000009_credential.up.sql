@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS apicredential (
+	id bytea NOT NULL,
+	name text NOT NULL,
+	secretHash bytea NOT NULL,
+	methods text[],
+	maxAmount bigint NOT NULL DEFAULT 0,
+	expiry timestamptz,
+	revoked boolean NOT NULL DEFAULT false,
+	created timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (id)
+);
+ALTER TABLE submarineswap ADD COLUMN credentialID bytea;
+CREATE INDEX IF NOT EXISTS submarineswap_credentialID_idx ON submarineswap (credentialID);
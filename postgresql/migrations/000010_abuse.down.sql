@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS submarineswap_state_created_idx;
+ALTER TABLE submarineswap DROP COLUMN clientIP;
+ALTER TABLE submarineswap DROP COLUMN created;
@@ -0,0 +1,3 @@
+ALTER TABLE submarineswap ADD COLUMN created timestamptz NOT NULL DEFAULT now();
+ALTER TABLE submarineswap ADD COLUMN clientIP text;
+CREATE INDEX IF NOT EXISTS submarineswap_state_created_idx ON submarineswap (state, created);
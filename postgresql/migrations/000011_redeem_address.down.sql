@@ -0,0 +1 @@
+ALTER TABLE submarineswap DROP COLUMN redeemAddress;
@@ -0,0 +1 @@
+ALTER TABLE submarineswap ADD COLUMN redeemAddress text;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS swapredeem_hash_idx;
+DROP TABLE swapredeem;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS swapredeem (
+	network text NOT NULL,
+	hash bytea NOT NULL,
+	txid bytea NOT NULL,
+	fee bigint NOT NULL,
+	created timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (network, txid)
+);
+CREATE INDEX IF NOT EXISTS swapredeem_hash_idx ON swapredeem (network, hash);
+INSERT INTO swapredeem (network, hash, txid, fee)
+	SELECT network, hash, redeemTxid, redeemFee FROM submarineswap WHERE redeemTxid IS NOT NULL
+	ON CONFLICT DO NOTHING;
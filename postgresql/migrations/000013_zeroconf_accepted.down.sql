@@ -0,0 +1 @@
+ALTER TABLE swapdeposit DROP COLUMN zeroConfAccepted;
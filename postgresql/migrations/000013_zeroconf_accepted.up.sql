@@ -0,0 +1 @@
+ALTER TABLE swapdeposit ADD COLUMN zeroConfAccepted boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+ALTER TABLE submarineswap ADD COLUMN funded timestamptz;
+UPDATE submarineswap SET funded=created WHERE state BETWEEN 1 AND 4;
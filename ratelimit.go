@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"swapper/chain"
+	"swapper/swapscript"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// limiter is a set of token buckets, one per key, refilled at rate tokens
+// per second up to burst.
+type limiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns a limiter allowing perMinute requests per minute and
+// key, all of them at once. A nil limiter, returned for perMinute 0, allows
+// everything.
+func newLimiter(perMinute int) *limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(perMinute),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from the bucket of key and reports whether there was
+// one.
+func (l *limiter) allow(key string) bool {
+	if l == nil {
+		return true
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		// Full buckets carry no state: forget them before growing the map.
+		if len(l.buckets) >= 10000 {
+			l.pruneLocked(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (l *limiter) pruneLocked(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// withRate returns a limiter allowing perMinute requests per minute and key:
+// l itself if it already does, otherwise a new limiter starting from the
+// buckets of l, so that a config reload doesn't refill them.
+func (l *limiter) withRate(perMinute int) *limiter {
+	n := newLimiter(perMinute)
+	if l == nil || n == nil {
+		return n
+	}
+	if n.rate == l.rate {
+		return l
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		n.buckets[key] = &bucket{tokens: min(b.tokens, n.burst), last: b.last}
+	}
+	return n
+}
+
+// rateLimitedMethods are the RPC methods creating rows in the database.
+var rateLimitedMethods = map[string]bool{
+	"SubSwapServiceInit": true,
+	"GetQuote":           true,
+}
+
+// loadAbuseLimits reads CREDENTIAL_RATE_LIMIT and IP_RATE_LIMIT, the swap
+// creations allowed per minute (default 60 and 20, 0 for no limit),
+// MAX_UNFUNDED_SWAPS (default 20) and UNFUNDED_SWAP_TTL (default 24h).
+func loadAbuseLimits(c *config) error {
+	limits := map[string]int{"CREDENTIAL_RATE_LIMIT": 60, "IP_RATE_LIMIT": 20}
+	for name := range limits {
+		s := os.Getenv(name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%v=%v: %w", name, s, err)
+		}
+		limits[name] = n
+	}
+	c.credentialLimiter = c.credentialLimiter.withRate(limits["CREDENTIAL_RATE_LIMIT"])
+	c.ipLimiter = c.ipLimiter.withRate(limits["IP_RATE_LIMIT"])
+
+	if s := os.Getenv("MAX_UNFUNDED_SWAPS"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("MAX_UNFUNDED_SWAPS=%v: %w", s, err)
+		}
+		c.maxUnfundedSwaps = n
+	}
+	if s := os.Getenv("UNFUNDED_SWAP_TTL"); s != "" {
+		ttl, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("UNFUNDED_SWAP_TTL=%v: %w", s, err)
+		}
+		if ttl <= 0 {
+			return fmt.Errorf("UNFUNDED_SWAP_TTL=%v not valid", s)
+		}
+		c.unfundedSwapTTL = ttl
+	}
+	return nil
+}
+
+// swapClient identifies who created a swap.
+type swapClient struct {
+	// credentialID is nil for unauthenticated clients.
+	credentialID []byte
+	ip           string
+}
+
+// clientFromContext returns the client of the request in ctx.
+func clientFromContext(ctx context.Context) swapClient {
+	c := swapClient{credentialID: credentialFromContext(ctx).credentialID()}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		c.ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(c.ip); err == nil {
+			c.ip = host
+		}
+	}
+	return c
+}
+
+// rateLimitInterceptor limits the rate of the swap creations per credential
+// and per IP. It runs after authInterceptor.
+func rateLimitInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !rateLimitedMethods[path.Base(info.FullMethod)] {
+		return handler(ctx, req)
+	}
+	cfg := currentConfig()
+	c := clientFromContext(ctx)
+	if c.credentialID != nil && !cfg.credentialLimiter.allow(string(c.credentialID)) {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	if c.ip != "" && !cfg.ipLimiter.allow(c.ip) {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return handler(ctx, req)
+}
+
+// checkUnfundedSwaps rejects the swap creations of a client which has
+// maxUnfundedSwaps swaps waiting for a deposit.
+func checkUnfundedSwaps(ctx context.Context, c swapClient) error {
+	maxUnfundedSwaps := currentConfig().maxUnfundedSwaps
+	if maxUnfundedSwaps == 0 {
+		return nil
+	}
+	n, err := countUnfundedSwaps(ctx, c)
+	if err != nil {
+		return err
+	}
+	if n >= maxUnfundedSwaps {
+		return status.Errorf(codes.ResourceExhausted, "too many unfunded swaps: %v", n)
+	}
+	return nil
+}
+
+// expireUnfundedSwaps expires the swaps of n which received no deposit
+// within unfundedSwapTTL and stops tracking their address. The chain is
+// checked first, so that a deposit the subscriber missed is recorded instead.
+func expireUnfundedSwaps(ctx context.Context, n *network) {
+	swaps, err := getExpirableSwaps(ctx, n.params.Name, time.Now().Add(-currentConfig().unfundedSwapTTL))
+	if err != nil {
+		slog.Error("getExpirableSwaps failed", "network", n.params.Name, "error", err)
+		return
+	}
+	expired := 0
+	for _, s := range swaps {
+		logger := swapLogger(n.params.Name, s.hash)
+		funded, err := recordMissedDeposits(ctx, n, s)
+		if err != nil {
+			logger.Warn("expiry postponed: chain check failed", "error", err)
+			continue
+		}
+		if funded {
+			continue
+		}
+		ok, err := expireSwap(ctx, n.params.Name, s.hash)
+		if err != nil {
+			logger.Error("expireSwap failed", "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		expired++
+		logger.Info("swap expired unfunded", "address", s.address)
+		untrackSwap(n, s)
+	}
+	observeSwapTransition(n.params.Name, swapStateExpired, expired)
+}
+
+// recordMissedDeposits records the utxos of the swap s of n found on chain or
+// in the mempool, and reports whether there were any.
+func recordMissedDeposits(ctx context.Context, n *network, s openSwap) (bool, error) {
+	address, err := swapscript.Address(s.script, n.params)
+	if err != nil {
+		return false, err
+	}
+	utxos, err := n.backend.GetUtxos(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	if reader, ok := n.backend.(chain.UnconfirmedUtxoReader); ok {
+		unconfirmed, err := reader.GetUnconfirmedUtxos(ctx, address)
+		if err != nil && !errors.Is(err, chain.ErrMempoolUnsupported) {
+			return false, err
+		}
+		utxos = append(utxos, unconfirmed...)
+	}
+
+	for _, u := range utxos {
+		// Record the block of the confirmation, so that checkReorgs can
+		// roll it back.
+		var blockHash []byte
+		if u.BlockHeight > 0 && n.hasher != nil {
+			h, err := n.hasher.BlockHash(ctx, u.BlockHeight)
+			if err != nil {
+				return false, err
+			}
+			blockHash = h[:]
+		}
+		swapLogger(n.params.Name, s.hash).Warn("missed deposit recorded", "outpoint", u.OutPoint,
+			"value", int64(u.Value), "height", u.BlockHeight)
+		if err := saveSwapDeposit(ctx, n.params.Name, s.hash, u.OutPoint, u.Value, u.BlockHeight, blockHash); err != nil {
+			return false, err
+		}
+	}
+	return len(utxos) > 0, nil
+}
+
+func untrackSwap(n *network, s openSwap) {
+	address := s.address
+	if address == "" {
+		a, err := swapscript.Address(s.script, n.params)
+		if err != nil {
+			return
+		}
+		address = a.EncodeAddress()
+	}
+	for _, t := range n.trackers() {
+		if err := t.UntrackAddress(address); err != nil {
+			slog.Error("UntrackAddress failed", "network", n.params.Name, "address", address, "error", err)
+		}
+	}
+}
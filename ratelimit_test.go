@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	l := newLimiter(60)
+
+	// The burst is allowed at once, then the bucket is empty.
+	for i := 0; i < 60; i++ {
+		if !l.allow("a") {
+			t.Fatalf("request %v of the burst refused", i)
+		}
+	}
+	if l.allow("a") {
+		t.Fatal("request above the burst allowed")
+	}
+	// Other keys have their own bucket.
+	if !l.allow("b") {
+		t.Fatal("request of another key refused")
+	}
+
+	// 60 per minute refill one token per second.
+	l.buckets["a"].last = l.buckets["a"].last.Add(-1500 * time.Millisecond)
+	if !l.allow("a") {
+		t.Fatal("request after refill refused")
+	}
+	if l.allow("a") {
+		t.Fatal("second request after a single token refill allowed")
+	}
+
+	// The refill is capped at the burst.
+	l.buckets["a"].last = l.buckets["a"].last.Add(-time.Hour)
+	for i := 0; i < 60; i++ {
+		if !l.allow("a") {
+			t.Fatalf("request %v after a long pause refused", i)
+		}
+	}
+	if l.allow("a") {
+		t.Fatal("refill above the burst")
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := newLimiter(0)
+	if l != nil {
+		t.Fatal("newLimiter(0) != nil")
+	}
+	for i := 0; i < 1000; i++ {
+		if !l.allow("a") {
+			t.Fatal("nil limiter refused a request")
+		}
+	}
+}
+
+func TestLimiterPrune(t *testing.T) {
+	l := newLimiter(1)
+	now := time.Now()
+	l.buckets["full"] = &bucket{tokens: 1, last: now}
+	l.buckets["empty"] = &bucket{tokens: 0, last: now}
+	l.pruneLocked(now)
+	if _, ok := l.buckets["full"]; ok {
+		t.Error("full bucket not pruned")
+	}
+	if _, ok := l.buckets["empty"]; !ok {
+		t.Error("empty bucket pruned")
+	}
+}
+
+func TestLimiterWithRate(t *testing.T) {
+	l := newLimiter(60)
+	for i := 0; i < 60; i++ {
+		l.allow("a")
+	}
+
+	if l.withRate(60) != l {
+		t.Fatal("limiter replaced without a rate change")
+	}
+	if l.withRate(0) != nil {
+		t.Fatal("limiter kept after being disabled")
+	}
+
+	// The emptied bucket stays empty at the new rate.
+	n := l.withRate(120)
+	if n.allow("a") {
+		t.Fatal("bucket refilled by a rate change")
+	}
+	if !n.allow("b") {
+		t.Fatal("request of another key refused")
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"swapper/chain"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+)
+
+const (
+	// refundDustLimit is the smallest excess returned to the payer. Smaller
+	// excesses don't pay for their output and are kept.
+	refundDustLimit btcutil.Amount = 546
+)
+
+// reconciliationStatus compares the deposits of a swap with its amount.
+type reconciliationStatus int16
+
+const (
+	// reconciliationOpen swaps have no amount to compare with.
+	reconciliationOpen reconciliationStatus = 0
+	// reconciliationExact deposits cover the amount and fees, with less
+	// than refundDustLimit left.
+	reconciliationExact reconciliationStatus = 1
+	// reconciliationUnderpaid deposits don't cover the amount and fees.
+	reconciliationUnderpaid reconciliationStatus = 2
+	// reconciliationOverpaid deposits exceed the amount and fees, and the
+	// excess is kept for lack of a refund address.
+	reconciliationOverpaid reconciliationStatus = 3
+	// reconciliationRefunded deposits exceed the amount and fees, and the
+	// redeem transaction returns the excess to the refund address.
+	reconciliationRefunded reconciliationStatus = 4
+)
+
+func (s reconciliationStatus) String() string {
+	switch s {
+	case reconciliationOpen:
+		return "open"
+	case reconciliationExact:
+		return "exact"
+	case reconciliationUnderpaid:
+		return "underpaid"
+	case reconciliationOverpaid:
+		return "overpaid"
+	case reconciliationRefunded:
+		return "refunded"
+	}
+	return fmt.Sprintf("reconciliationStatus(%d)", int16(s))
+}
+
+// reconciliation is the outcome of comparing the deposits of a swap, which
+// may be several, with its amount.
+type reconciliation struct {
+	status   reconciliationStatus
+	deposits int
+	total    btcutil.Amount
+	amount   btcutil.Amount
+	fee      btcutil.Amount
+	excess   btcutil.Amount
+}
+
+func (r reconciliation) String() string {
+	return fmt.Sprintf("%v: %v deposits, total %v, amount %v, fee %v, excess %v",
+		r.status, r.deposits, r.total, r.amount, r.fee, r.excess)
+}
+
+// reconcile compares the total of utxos with amount plus fee. amount is the
+// invoice amount plus the service fee, 0 if unknown.
+func reconcile(utxos []chain.Utxo, amount, fee btcutil.Amount) reconciliation {
+	r := reconciliation{deposits: len(utxos), amount: amount, fee: fee}
+	for _, u := range utxos {
+		r.total += u.Value
+	}
+	switch {
+	case amount == 0:
+		r.status = reconciliationOpen
+	case r.total < amount+fee:
+		r.status = reconciliationUnderpaid
+	case r.total-amount-fee >= refundDustLimit:
+		r.status = reconciliationOverpaid
+		r.excess = r.total - amount - fee
+	default:
+		r.status = reconciliationExact
+	}
+	return r
+}
+
+// redeemTxFee returns the fee of the redeem transaction tx once its inputs
+// are signed.
+func redeemTxFee(tx *wire.MsgTx, feePerKw chainfee.SatPerKWeight) btcutil.Amount {
+	weight := 4*tx.SerializeSizeStripped() + redeemWitnessInputSize*len(tx.TxIn)
+	return feePerKw.FeeForWeight(int64(weight))
+}
+
+// reconcileRedeemTx reconciles the utxos spent by tx with amount, the amount
+// due without the miner fee, and sets the value of the redeem output, the
+// only output of tx. If the utxos exceed amount and fees and refundAddress is
+// not nil, an output returning the excess to refundAddress is added.
+//
+// The payer is charged the miner fee at chargedPerKw, the rate locked by the
+// quote, while tx pays feePerKw: the redeem output takes the difference, so
+// that the excess refunded doesn't depend on the fees at redeem time.
+func reconcileRedeemTx(tx *wire.MsgTx, utxos []chain.Utxo, amount btcutil.Amount, refundAddress btcutil.Address, chargedPerKw, feePerKw chainfee.SatPerKWeight) (reconciliation, error) {
+	r := reconcile(utxos, amount, redeemTxFee(tx, chargedPerKw))
+	if r.status == reconciliationOverpaid && refundAddress != nil {
+		refundScript, err := txscript.PayToAddrScript(refundAddress)
+		if err != nil {
+			return r, err
+		}
+		tx.AddTxOut(&wire.TxOut{PkScript: refundScript})
+		withRefund := reconcile(utxos, amount, redeemTxFee(tx, chargedPerKw))
+		if withRefund.status == reconciliationOverpaid {
+			r = withRefund
+			r.status = reconciliationRefunded
+			tx.TxOut[1].Value = int64(r.excess)
+		} else {
+			// The refund output doesn't pay for itself.
+			tx.TxOut = tx.TxOut[:1]
+		}
+	}
+
+	redeemed := r.total - redeemTxFee(tx, feePerKw)
+	if r.status == reconciliationRefunded {
+		redeemed -= r.excess
+	}
+	tx.TxOut[0].Value = int64(redeemed)
+	return r, nil
+}
+
+// swapPricing is what the payer of a swap owes besides the miner fee.
+type swapPricing struct {
+	// amount is the invoice amount, 0 if unknown.
+	amount     btcutil.Amount
+	serviceFee btcutil.Amount
+	// feeRate is the miner fee rate locked by the quote, in sat/vbyte, 0
+	// without quote.
+	feeRate       uint64
+	quoteID       []byte
+	refundAddress string
+}
+
+// chargedFeePerKw returns the miner fee rate charged to the payer: the quoted
+// one, or current without quote.
+func (p swapPricing) chargedFeePerKw(current chainfee.SatPerKWeight) chainfee.SatPerKWeight {
+	if p.feeRate == 0 {
+		return current
+	}
+	return chainfee.SatPerKVByte(p.feeRate * 1000).FeePerKWeight()
+}
+
+// due returns the amount plus the service fee.
+func (p swapPricing) due() btcutil.Amount {
+	if p.amount == 0 {
+		return 0
+	}
+	return p.amount + p.serviceFee
+}
+
+// refund decodes the refund address, nil if none.
+func (p swapPricing) refund(net *chaincfg.Params) (btcutil.Address, error) {
+	if p.refundAddress == "" {
+		return nil, nil
+	}
+	address, err := btcutil.DecodeAddress(p.refundAddress, net)
+	if err != nil {
+		return nil, fmt.Errorf("refund address %v: %w", p.refundAddress, err)
+	}
+	return address, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"swapper/chain"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+)
+
+func testAddress(t *testing.T, b byte) btcutil.Address {
+	t.Helper()
+	a, err := btcutil.NewAddressWitnessPubKeyHash(bytes.Repeat([]byte{b}, 20), &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// testRedeemTx returns a redeem transaction spending one utxo per value to
+// redeemAddress, and the utxos.
+func testRedeemTx(t *testing.T, redeemAddress btcutil.Address, values ...btcutil.Amount) (*wire.MsgTx, []chain.Utxo) {
+	t.Helper()
+	tx := wire.NewMsgTx(1)
+	var utxos []chain.Utxo
+	for i, v := range values {
+		op := wire.OutPoint{Hash: chainhash.Hash{byte(i + 1)}, Index: uint32(i)}
+		utxos = append(utxos, chain.Utxo{Value: v, OutPoint: op})
+		tx.AddTxIn(wire.NewTxIn(&op, nil, nil))
+	}
+	script, err := txscript.PayToAddrScript(redeemAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.AddTxOut(&wire.TxOut{PkScript: script})
+	return tx, utxos
+}
+
+func TestReconcileRedeemTx(t *testing.T) {
+	const feePerKw = chainfee.SatPerKWeight(2500)
+	// risenPerKw is paid when the fees rose since the quote.
+	const risenPerKw = 2 * feePerKw
+	redeemAddress := testAddress(t, 1)
+	refundAddress := testAddress(t, 2)
+
+	// The fees of redeem transactions with one and two inputs, and of the
+	// refund output.
+	tx1, _ := testRedeemTx(t, redeemAddress, 0)
+	fee1 := redeemTxFee(tx1, feePerKw)
+	risenFee1 := redeemTxFee(tx1, risenPerKw)
+	tx2, _ := testRedeemTx(t, redeemAddress, 0, 0)
+	fee2 := redeemTxFee(tx2, feePerKw)
+	refundScript, _ := txscript.PayToAddrScript(refundAddress)
+	tx1.AddTxOut(&wire.TxOut{PkScript: refundScript})
+	refundOutputFee := redeemTxFee(tx1, feePerKw) - fee1
+	risenRefundedFee1 := redeemTxFee(tx1, risenPerKw)
+
+	const amount = 100000
+	for _, tt := range []struct {
+		name          string
+		values        []btcutil.Amount
+		amount        btcutil.Amount
+		refundAddress btcutil.Address
+		// broadcastPerKw is the fee rate paid by the transaction, feePerKw
+		// if 0. The payer is always charged feePerKw.
+		broadcastPerKw chainfee.SatPerKWeight
+		wantStatus     reconciliationStatus
+		// wantOutputs are the values of the outputs of the redeem
+		// transaction, the redeem output first.
+		wantOutputs []int64
+	}{
+		{
+			name:        "open",
+			values:      []btcutil.Amount{50000},
+			wantStatus:  reconciliationOpen,
+			wantOutputs: []int64{int64(50000 - fee1)},
+		},
+		{
+			name:        "underpaid",
+			values:      []btcutil.Amount{amount + fee1 - 1},
+			amount:      amount,
+			wantStatus:  reconciliationUnderpaid,
+			wantOutputs: []int64{amount - 1},
+		},
+		{
+			name:        "exact",
+			values:      []btcutil.Amount{amount + fee1},
+			amount:      amount,
+			wantStatus:  reconciliationExact,
+			wantOutputs: []int64{amount},
+		},
+		{
+			name:        "exact below dust",
+			values:      []btcutil.Amount{amount + fee1 + refundDustLimit - 1},
+			amount:      amount,
+			wantStatus:  reconciliationExact,
+			wantOutputs: []int64{int64(amount + refundDustLimit - 1)},
+		},
+		{
+			name:        "exact with two deposits",
+			values:      []btcutil.Amount{40000, amount + fee2 - 40000},
+			amount:      amount,
+			wantStatus:  reconciliationExact,
+			wantOutputs: []int64{amount},
+		},
+		{
+			name:        "overpaid without refund address",
+			values:      []btcutil.Amount{amount + fee1 + 10000},
+			amount:      amount,
+			wantStatus:  reconciliationOverpaid,
+			wantOutputs: []int64{amount + 10000},
+		},
+		{
+			name:          "overpaid refunded",
+			values:        []btcutil.Amount{amount + fee1 + 10000},
+			amount:        amount,
+			refundAddress: refundAddress,
+			wantStatus:    reconciliationRefunded,
+			wantOutputs:   []int64{amount, int64(10000 - refundOutputFee)},
+		},
+		{
+			name:          "overpaid refund not worth its fee",
+			values:        []btcutil.Amount{amount + fee1 + refundDustLimit},
+			amount:        amount,
+			refundAddress: refundAddress,
+			wantStatus:    reconciliationOverpaid,
+			wantOutputs:   []int64{int64(amount + refundDustLimit)},
+		},
+		{
+			name:           "exact, fees risen",
+			values:         []btcutil.Amount{amount + fee1},
+			amount:         amount,
+			broadcastPerKw: risenPerKw,
+			wantStatus:     reconciliationExact,
+			wantOutputs:    []int64{int64(amount + fee1 - risenFee1)},
+		},
+		{
+			name:           "overpaid refunded, fees risen",
+			values:         []btcutil.Amount{amount + fee1 + 10000},
+			amount:         amount,
+			refundAddress:  refundAddress,
+			broadcastPerKw: risenPerKw,
+			wantStatus:     reconciliationRefunded,
+			wantOutputs: []int64{int64(amount + fee1 + refundOutputFee - risenRefundedFee1),
+				int64(10000 - refundOutputFee)},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			broadcastPerKw := tt.broadcastPerKw
+			if broadcastPerKw == 0 {
+				broadcastPerKw = feePerKw
+			}
+			tx, utxos := testRedeemTx(t, redeemAddress, tt.values...)
+			r, err := reconcileRedeemTx(tx, utxos, tt.amount, tt.refundAddress, feePerKw, broadcastPerKw)
+			if err != nil {
+				t.Fatalf("reconcileRedeemTx() error: %v", err)
+			}
+			if r.status != tt.wantStatus {
+				t.Errorf("status = %v, want %v (%v)", r.status, tt.wantStatus, r)
+			}
+			var outputs []int64
+			for _, o := range tx.TxOut {
+				outputs = append(outputs, o.Value)
+			}
+			if len(outputs) != len(tt.wantOutputs) {
+				t.Fatalf("outputs = %v, want %v", outputs, tt.wantOutputs)
+			}
+			for i := range outputs {
+				if outputs[i] != tt.wantOutputs[i] {
+					t.Errorf("outputs = %v, want %v", outputs, tt.wantOutputs)
+				}
+			}
+			// Nothing is created or lost: the outputs and the fee add up to
+			// the deposits.
+			var total, sum btcutil.Amount
+			for _, v := range tt.values {
+				total += v
+			}
+			for _, o := range outputs {
+				sum += btcutil.Amount(o)
+			}
+			if fee := redeemTxFee(tx, broadcastPerKw); sum+fee != total {
+				t.Errorf("outputs %v + fee %v != deposits %v", sum, fee, total)
+			}
+		})
+	}
+}
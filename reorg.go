@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"swapper/chain"
+	"swapper/swapscript"
+)
+
+const (
+	// reorgCheckDepth is how deep below the tip confirmations are checked
+	// against the best chain.
+	reorgCheckDepth = 144
+)
+
+// loadSafeDepth reads SAFE_DEPTH.
+func loadSafeDepth(c *config) error {
+	s := os.Getenv("SAFE_DEPTH")
+	if s == "" {
+		return nil
+	}
+	d, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return fmt.Errorf("SAFE_DEPTH=%v: %w", s, err)
+	}
+	if d < 1 || d > reorgCheckDepth {
+		return fmt.Errorf("SAFE_DEPTH=%v not in [1, %v]", s, reorgCheckDepth)
+	}
+	c.safeDepth = int32(d)
+	return nil
+}
+
+// safeHeight returns the highest block height whose transactions have safe
+// depth when the tip is at height tip.
+func safeHeight(tip int32) int32 {
+	return tip - currentConfig().safeDepth + 1
+}
+
+// safeUtxos returns the utxos having safe depth when the tip is at height
+// tip.
+func safeUtxos(utxos []chain.Utxo, tip int32) []chain.Utxo {
+	var safe []chain.Utxo
+	for _, u := range utxos {
+		if u.BlockHeight > 0 && u.BlockHeight <= safeHeight(tip) {
+			safe = append(safe, u)
+		}
+	}
+	return safe
+}
+
+// checkReorgs compares the recorded confirmations of n not deeper than
+// reorgCheckDepth below tip with the hashes of the best chain, and rolls back
+// those which were reorganized out. It needs enough providers returning block
+// hashes, see network.hasher.
+func checkReorgs(ctx context.Context, n *network, tip int32) {
+	hasher := n.hasher
+	if hasher == nil {
+		return
+	}
+	confirmations, err := getConfirmations(ctx, n.params.Name, tip-reorgCheckDepth)
+	if err != nil {
+		slog.Error("getConfirmations failed", "network", n.params.Name, "error", err)
+		return
+	}
+
+	hashes := make(map[int32][]byte)
+	for _, c := range confirmations {
+		hash, ok := hashes[c.height]
+		if !ok {
+			h, err := hasher.BlockHash(ctx, c.height)
+			if err != nil {
+				slog.Warn("BlockHash failed", "network", n.params.Name, "height", c.height, "error", err)
+				continue
+			}
+			hash = h[:]
+			hashes[c.height] = hash
+		}
+		if bytes.Equal(hash, c.blockHash) {
+			continue
+		}
+
+		logger := swapLogger(n.params.Name, c.hash).With("outpoint", c.outPoint)
+		logger.Warn("confirmation reorganized out", "spend", c.spend, "height", c.height)
+		reopened, err := rollbackConfirmation(ctx, n.params.Name, c)
+		if err != nil {
+			logger.Error("rollbackConfirmation failed", "error", err)
+			continue
+		}
+		if reopened {
+			retrackSwap(ctx, n, c.hash)
+		}
+	}
+}
+
+// retrackSwap tracks again the address of the swap hash of n, reopened after
+// the reorg of its spend.
+func retrackSwap(ctx context.Context, n *network, hash []byte) {
+	s, err := getSwap(ctx, n.params.Name, hash)
+	if err != nil {
+		swapLogger(n.params.Name, hash).Error("getSwap failed", "error", err)
+		return
+	}
+	if s == nil {
+		return
+	}
+	address, err := swapscript.Address(s.script, n.params)
+	if err != nil {
+		swapLogger(n.params.Name, hash).Error("swap address failed", "error", err)
+		return
+	}
+	trackSwapAddress(n.params, address, s.created)
+}
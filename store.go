@@ -4,63 +4,1113 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"runtime"
+	"strings"
+	"swapper/chain"
+	"swapper/swapscript"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	pgxPool *pgxpool.Pool
+	pgxPool *dbPool
 )
 
 func pgConnect() error {
-	var err error
-	pgxPool, err = pgxpool.Connect(context.Background(), os.Getenv("DATABASE_URL"))
+	pool, err := pgxpool.Connect(context.Background(), os.Getenv("DATABASE_URL"))
 	if err != nil {
 		return fmt.Errorf("pgxpool.Connect(%v): %w", os.Getenv("DATABASE_URL"), err)
 	}
+	pgxPool = &dbPool{pool}
 	return nil
 }
-func saveSwapperSubmarineData(netID byte, hash []byte, lockHeight int64, swapperKey []byte, script []byte) error {
+
+// dbPool times and traces the queries of the store functions, labelled
+// with the name of the function running them. Transactions are timed from
+// Begin to Commit or Rollback.
+type dbPool struct {
+	*pgxpool.Pool
+}
+
+// storeFunc returns the name of the function calling the dbPool method.
+func storeFunc() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+	name := runtime.FuncForPC(pc).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// query is a query being timed and traced.
+type query struct {
+	name  string
+	start time.Time
+	span  trace.Span
+}
+
+func startQuery(ctx context.Context, name, sql string) (context.Context, *query) {
+	ctx, span := tracer.Start(ctx, "db "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", sql),
+		))
+	return ctx, &query{name: name, start: time.Now(), span: span}
+}
+
+func (q *query) end(err error) {
+	observeQuery(q.name, q.start)
+	endSpan(q.span, err)
+}
+
+func (p *dbPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, q := startQuery(ctx, storeFunc(), sql)
+	tag, err := p.Pool.Exec(ctx, sql, args...)
+	q.end(err)
+	return tag, err
+}
+
+// Query is timed until the first row is available.
+func (p *dbPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, q := startQuery(ctx, storeFunc(), sql)
+	rows, err := p.Pool.Query(ctx, sql, args...)
+	q.end(err)
+	return rows, err
+}
+
+// QueryRow is timed until the row is scanned, which is when pgx reports the
+// errors of the query.
+func (p *dbPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, q := startQuery(ctx, storeFunc(), sql)
+	return &dbRow{Row: p.Pool.QueryRow(ctx, sql, args...), query: q}
+}
+
+type dbRow struct {
+	pgx.Row
+	query *query
+}
+
+func (r *dbRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	// No row is an answer, not a failure.
+	if err == pgx.ErrNoRows {
+		r.query.end(nil)
+	} else {
+		r.query.end(err)
+	}
+	return err
+}
+
+func (p *dbPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	ctx, q := startQuery(ctx, storeFunc(), "BEGIN")
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		q.end(err)
+		return nil, err
+	}
+	return &dbTx{Tx: tx, query: q}, nil
+}
+
+type dbTx struct {
+	pgx.Tx
+	query *query
+	done  bool
+}
+
+func (t *dbTx) Commit(ctx context.Context) error {
+	err := t.Tx.Commit(ctx)
+	t.end(err)
+	return err
+}
+
+func (t *dbTx) Rollback(ctx context.Context) error {
+	err := t.Tx.Rollback(ctx)
+	t.end(nil)
+	return err
+}
+
+func (t *dbTx) end(err error) {
+	if !t.done {
+		t.done = true
+		t.query.end(err)
+	}
+}
+func saveSwapperSubmarineData(ctx context.Context, network string, netID byte, hash []byte, typ swapscript.Type, lockHeight int64, swapperKey []byte, script []byte, address string, pricing swapPricing, client swapClient) error {
 
 	if len(swapperKey) != btcec.PrivKeyBytesLen {
 		return errors.New("swapperKey not valid")
 	}
 
-	commandTag, err := pgxPool.Exec(context.Background(),
+	// No ON CONFLICT: the caller checked the hash is new, but a concurrent
+	// creation of the same hash must fail rather than hand out a script
+	// whose swapper key isn't stored.
+	_, err := pgxPool.Exec(ctx,
 		`INSERT INTO
-	submarineswap (netID, hash, lockHeight, swapperKey,script)
-	VALUES ($1, $2, $3, $4, $5)
-	ON CONFLICT DO NOTHING`,
-		netID, hash, lockHeight, swapperKey, script)
-	log.Printf("submarineswap(%x, %x, %v, %x,%x) rows: %v err: %v",
-		netID, hash, lockHeight, swapperKey, script, commandTag.RowsAffected(), err)
+	submarineswap (network, netID, hash, probingHash, swapType, lockHeight, swapperKey,script, address,
+		amount, serviceFee, feeRate, quoteID, refundAddress, credentialID, clientIP)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''), $15, NULLIF($16, ''))`,
+		network, netID, hash, probingHash(hash), typ, lockHeight, swapperKey, script, address,
+		int64(pricing.amount), int64(pricing.serviceFee), int64(pricing.feeRate), pricing.quoteID, pricing.refundAddress,
+		client.credentialID, client.ip)
 	if err != nil {
-		return fmt.Errorf("saveSwapperSubmarineData(%x, %x, %v, %x, %x) error: %w",
-			netID, hash, lockHeight, swapperKey, script, err)
+		return fmt.Errorf("saveSwapperSubmarineData(%v, %x, %x, %v, %v, %x) error: %w",
+			network, netID, hash, typ, lockHeight, script, err)
 	}
+	slog.Debug("swap saved", "network", network, "hash", hash, "type", typ,
+		"lock_height", lockHeight, "script", script)
 
 	return nil
 }
 
-func getSwapperSubmarineData(hash []byte) (lockHeight int64, swapperKey, script []byte, err error) {
-
-	var netID byte
+// getSwapperSubmarineData returns the swap data stored for the payment hash
+// on network. A probing hash never matches here: only the owner of the real
+// hash can redeem. If no swap exists, script is nil and err is nil.
+func getSwapperSubmarineData(ctx context.Context, network string, hash []byte) (typ swapscript.Type, lockHeight int64, swapperKey, script []byte, err error) {
 
-	err = pgxPool.QueryRow(context.Background(),
-		`SELECT netID, hash, lockHeight, swapperKey,script
+	err = pgxPool.QueryRow(ctx,
+		`SELECT swapType, lockHeight, swapperKey, script
 			FROM submarineswap
-			WHERE hash=$1 OR sha256('probing-01:' || hash)=$1`,
-		hash).Scan(&netID, &hash, &lockHeight, &swapperKey, &script)
+			WHERE network=$1 AND hash=$2`,
+		network, hash).Scan(&typ, &lockHeight, &swapperKey, &script)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			err = nil
 		}
-		return 0, nil, nil, err
+		return 0, 0, nil, nil, err
+	}
+
+	return typ, lockHeight, swapperKey, script, nil
+}
+
+// hashMatch tells which of the stored hashes of a swap a lookup matched.
+type hashMatch int
+
+const (
+	hashMatchNone hashMatch = iota
+	hashMatchPayment
+	hashMatchProbing
+)
+
+// matchSwapperSubmarineHash looks hash up on network both as a payment hash
+// and as a probing hash and returns which variant matched.
+func matchSwapperSubmarineHash(ctx context.Context, network string, hash []byte) (hashMatch, error) {
+
+	var isPaymentHash bool
+	err := pgxPool.QueryRow(ctx,
+		`SELECT hash=$2
+			FROM submarineswap
+			WHERE network=$1 AND (hash=$2 OR probingHash=$2)
+			LIMIT 1`,
+		network, hash).Scan(&isPaymentHash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return hashMatchNone, nil
+		}
+		return hashMatchNone, fmt.Errorf("matchSwapperSubmarineHash(%v, %x) error: %w", network, hash, err)
+	}
+
+	if isPaymentHash {
+		return hashMatchPayment, nil
+	}
+	return hashMatchProbing, nil
+}
+
+// probingHashExists reports whether a swap exists on network whose probing
+// hash is probingHash. It never matches on the payment hash.
+func probingHashExists(ctx context.Context, network string, probingHash []byte) (bool, error) {
+
+	var exists bool
+	err := pgxPool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM submarineswap WHERE network=$1 AND probingHash=$2)`,
+		network, probingHash).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("probingHashExists(%v, %x) error: %w", network, probingHash, err)
+	}
+
+	return exists, nil
+}
+
+// swapState is the lifecycle state of a swap, stored in submarineswap.state.
+type swapState int16
+
+const (
+	// swapStateCreated swaps have no deposit at safe depth yet.
+	swapStateCreated swapState = 0
+	// swapStateFunded swaps have at least one deposit at safe depth, so the
+	// invoice can be paid.
+	swapStateFunded swapState = 1
+	// swapStateClaimed swaps were spent through the claim path by our redeem
+	// transaction.
+	swapStateClaimed swapState = 2
+	// swapStateClaimedByThirdParty swaps were spent through the claim path
+	// by a transaction we didn't build.
+	swapStateClaimedByThirdParty swapState = 3
+	// swapStateRefunded swaps were spent back to the payer.
+	swapStateRefunded swapState = 4
+	// swapStateExpired swaps received no deposit in time and aren't
+	// watched anymore.
+	swapStateExpired swapState = 5
+)
+
+func (s swapState) String() string {
+	switch s {
+	case swapStateCreated:
+		return "created"
+	case swapStateFunded:
+		return "funded"
+	case swapStateClaimed:
+		return "claimed"
+	case swapStateClaimedByThirdParty:
+		return "claimed by third party"
+	case swapStateRefunded:
+		return "refunded"
+	case swapStateExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("swapState(%d)", int16(s))
+}
+
+// final reports whether the swap outputs were spent, or the swap expired
+// before receiving any.
+func (s swapState) final() bool {
+	return s >= swapStateClaimed
+}
+
+// openSwap is a swap whose outputs may still be unspent.
+type openSwap struct {
+	hash    []byte
+	script  []byte
+	address string
+	created time.Time
+}
+
+// getOpenSwaps returns the swaps on network not in a final state. address is
+// empty for swaps created before it was stored.
+func getOpenSwaps(ctx context.Context, network string) ([]openSwap, error) {
+
+	rows, err := pgxPool.Query(ctx,
+		`SELECT hash, script, COALESCE(address, ''), created
+			FROM submarineswap
+			WHERE network=$1 AND state < $2`,
+		network, swapStateClaimed)
+	if err != nil {
+		return nil, fmt.Errorf("getOpenSwaps(%v) error: %w", network, err)
+	}
+	defer rows.Close()
+
+	var swaps []openSwap
+	for rows.Next() {
+		var s openSwap
+		if err := rows.Scan(&s.hash, &s.script, &s.address, &s.created); err != nil {
+			return nil, fmt.Errorf("getOpenSwaps(%v) error: %w", network, err)
+		}
+		swaps = append(swaps, s)
+	}
+	return swaps, rows.Err()
+}
+
+// setSwapAddress stores the address of a swap created before addresses were
+// stored.
+func setSwapAddress(ctx context.Context, network string, hash []byte, address string) error {
+
+	_, err := pgxPool.Exec(ctx,
+		`UPDATE submarineswap SET address=$3 WHERE network=$1 AND hash=$2`,
+		network, hash, address)
+	if err != nil {
+		return fmt.Errorf("setSwapAddress(%v, %x, %v) error: %w", network, hash, address, err)
+	}
+	return nil
+}
+
+// getSwapByAddress returns the payment hash and state of the swap paying to
+// address on network. If no swap exists, hash is nil and err is nil.
+func getSwapByAddress(ctx context.Context, network, address string) (hash []byte, state swapState, err error) {
+
+	err = pgxPool.QueryRow(ctx,
+		`SELECT hash, state FROM submarineswap WHERE network=$1 AND address=$2`,
+		network, address).Scan(&hash, &state)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("getSwapByAddress(%v, %v) error: %w", network, address, err)
+	}
+	return hash, state, nil
+}
+
+// saveSwapDeposit records a deposit to the swap hash, or updates its
+// confirmation. blockHeight is 0 and blockHash nil for unconfirmed deposits.
+// A recorded confirmation is never cleared here: notifications may come late
+// or twice, and only rollbackConfirmation undoes a confirmation reorganized
+// out.
+func saveSwapDeposit(ctx context.Context, network string, hash []byte, op wire.OutPoint, value btcutil.Amount, blockHeight int32, blockHash []byte) error {
+
+	_, err := pgxPool.Exec(ctx,
+		`INSERT INTO
+	swapdeposit (network, hash, txid, vout, value, blockHeight, blockHash)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (network, txid, vout) DO UPDATE SET blockHeight=EXCLUDED.blockHeight, blockHash=EXCLUDED.blockHash
+		WHERE EXCLUDED.blockHash IS NOT NULL`,
+		network, hash, op.Hash[:], op.Index, int64(value), blockHeight, blockHash)
+	if err != nil {
+		return fmt.Errorf("saveSwapDeposit(%v, %x, %v) error: %w", network, hash, op, err)
+	}
+	return nil
+}
+
+// updateFundedStates moves the open swaps on network to swapStateFunded if
+// one of their deposits confirmed at or below safeHeight, and back to
+// swapStateCreated otherwise. Expired swaps which received a deposit since
+// are reopened the same way. It returns the number of swaps funded for the
+// first time, so that a swap going back to swapStateCreated on a reorg and
+// funded again isn't counted twice, and the reopened swaps.
+func updateFundedStates(ctx context.Context, network string, safeHeight int32) (int, []openSwap, error) {
+
+	rows, err := pgxPool.Query(ctx,
+		`UPDATE submarineswap s SET state=v.state,
+				funded=CASE WHEN v.state=$3 THEN COALESCE(s.funded, now()) ELSE s.funded END
+			FROM (SELECT network, hash, o.state=$6 AS expired, o.funded IS NULL AS unfunded, CASE WHEN EXISTS(
+					SELECT 1 FROM swapdeposit d
+					WHERE d.network=o.network AND d.hash=o.hash AND d.blockHeight>0 AND d.blockHeight<=$2
+				) THEN $3 ELSE $4 END AS state
+				FROM submarineswap o WHERE o.network=$1 AND (o.state<$5 OR o.state=$6 AND EXISTS(
+					SELECT 1 FROM swapdeposit d WHERE d.network=o.network AND d.hash=o.hash))) v
+			WHERE s.network=v.network AND s.hash=v.hash AND s.state<>v.state
+			RETURNING s.state, v.expired, v.unfunded, s.hash, s.script, COALESCE(s.address, ''), s.created`,
+		network, safeHeight, swapStateFunded, swapStateCreated, swapStateClaimed, swapStateExpired)
+	if err != nil {
+		return 0, nil, fmt.Errorf("updateFundedStates(%v, %v) error: %w", network, safeHeight, err)
+	}
+	defer rows.Close()
+
+	funded := 0
+	var reopened []openSwap
+	for rows.Next() {
+		var state swapState
+		var expired, unfunded bool
+		var s openSwap
+		if err := rows.Scan(&state, &expired, &unfunded, &s.hash, &s.script, &s.address, &s.created); err != nil {
+			return 0, nil, fmt.Errorf("updateFundedStates(%v, %v) error: %w", network, safeHeight, err)
+		}
+		if state == swapStateFunded && unfunded {
+			funded++
+		}
+		if expired {
+			reopened = append(reopened, s)
+		}
+	}
+	return funded, reopened, rows.Err()
+}
+
+// unspentDeposit is a deposit whose spend wasn't seen yet, with the data of
+// its swap needed to classify the spend.
+type unspentDeposit struct {
+	hash        []byte
+	outPoint    wire.OutPoint
+	blockHeight int32
+	script      []byte
+	// redeemTxids are the redeem transactions we broadcast for the swap.
+	redeemTxids [][]byte
+}
+
+// getUnspentDeposits returns the deposits on network whose spend wasn't
+// recorded.
+func getUnspentDeposits(ctx context.Context, network string) ([]unspentDeposit, error) {
+
+	rows, err := pgxPool.Query(ctx,
+		`SELECT d.hash, d.txid, d.vout, d.blockHeight, s.script,
+				ARRAY(SELECT r.txid FROM swapredeem r WHERE r.network=s.network AND r.hash=s.hash)
+			FROM swapdeposit d
+			JOIN submarineswap s ON s.network=d.network AND s.hash=d.hash
+			WHERE d.network=$1 AND d.spendTxid IS NULL`,
+		network)
+	if err != nil {
+		return nil, fmt.Errorf("getUnspentDeposits(%v) error: %w", network, err)
+	}
+	defer rows.Close()
+
+	var deposits []unspentDeposit
+	for rows.Next() {
+		var d unspentDeposit
+		var txid []byte
+		var vout int64
+		if err := rows.Scan(&d.hash, &txid, &vout, &d.blockHeight, &d.script, &d.redeemTxids); err != nil {
+			return nil, fmt.Errorf("getUnspentDeposits(%v) error: %w", network, err)
+		}
+		if err := d.outPoint.Hash.SetBytes(txid); err != nil {
+			return nil, fmt.Errorf("getUnspentDeposits(%v) error: %w", network, err)
+		}
+		d.outPoint.Index = uint32(vout)
+		deposits = append(deposits, d)
 	}
+	return deposits, rows.Err()
+}
+
+// setSwapSpent records that the deposit op of the swap hash was spent by
+// spendTxid through path in the block blockHash at blockHeight, and moves the
+// swap to state. preimage is stored when not nil. It reports whether the swap
+// changed state, which it doesn't for the other deposits spent by the same
+// transaction.
+func setSwapSpent(ctx context.Context, network string, hash []byte, op wire.OutPoint, spendTxid chainhash.Hash, path swapscript.Path, blockHeight int32, blockHash []byte, state swapState, preimage []byte) (bool, error) {
 
-	return 0, nil, nil, nil
+	tx, err := pgxPool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("setSwapSpent(%v, %x, %v) error: %w", network, hash, op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE swapdeposit SET spendTxid=$4, spendPath=$5, spendBlockHeight=$6, spendBlockHash=$7
+			WHERE network=$1 AND txid=$2 AND vout=$3`,
+		network, op.Hash[:], op.Index, spendTxid[:], path, blockHeight, blockHash)
+	if err != nil {
+		return false, fmt.Errorf("setSwapSpent(%v, %x, %v) error: %w", network, hash, op, err)
+	}
+	commandTag, err := tx.Exec(ctx,
+		`UPDATE submarineswap SET state=$3 WHERE network=$1 AND hash=$2 AND state<>$3`,
+		network, hash, state)
+	if err != nil {
+		return false, fmt.Errorf("setSwapSpent(%v, %x, %v) error: %w", network, hash, op, err)
+	}
+	if preimage != nil {
+		_, err = tx.Exec(ctx,
+			`UPDATE submarineswap SET preimage=$3 WHERE network=$1 AND hash=$2`,
+			network, hash, preimage)
+		if err != nil {
+			return false, fmt.Errorf("setSwapSpent(%v, %x, %v) error: %w", network, hash, op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("setSwapSpent(%v, %x, %v) error: %w", network, hash, op, err)
+	}
+	return commandTag.RowsAffected() > 0, nil
+}
+
+// getRedeemFee returns the fee of our redeem transaction txid on network, 0
+// if unknown.
+func getRedeemFee(ctx context.Context, network string, txid chainhash.Hash) (btcutil.Amount, error) {
+
+	var fee int64
+	err := pgxPool.QueryRow(ctx,
+		`SELECT fee FROM swapredeem WHERE network=$1 AND txid=$2`,
+		network, txid[:]).Scan(&fee)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getRedeemFee(%v, %v) error: %w", network, txid, err)
+	}
+	return btcutil.Amount(fee), nil
+}
+
+// confirmation is the block in which a deposit, or its spend, confirmed.
+type confirmation struct {
+	hash      []byte
+	outPoint  wire.OutPoint
+	spend     bool
+	height    int32
+	blockHash []byte
+}
+
+// getConfirmations returns the confirmations on network, of deposits and of
+// their spends, at or above minHeight.
+func getConfirmations(ctx context.Context, network string, minHeight int32) ([]confirmation, error) {
+
+	rows, err := pgxPool.Query(ctx,
+		`SELECT hash, txid, vout, false, blockHeight, blockHash
+			FROM swapdeposit
+			WHERE network=$1 AND blockHeight>=$2 AND blockHash IS NOT NULL
+		UNION ALL
+		SELECT hash, txid, vout, true, spendBlockHeight, spendBlockHash
+			FROM swapdeposit
+			WHERE network=$1 AND spendBlockHeight>=$2 AND spendBlockHash IS NOT NULL`,
+		network, minHeight)
+	if err != nil {
+		return nil, fmt.Errorf("getConfirmations(%v, %v) error: %w", network, minHeight, err)
+	}
+	defer rows.Close()
+
+	var confirmations []confirmation
+	for rows.Next() {
+		var c confirmation
+		var txid []byte
+		var vout, height int64
+		if err := rows.Scan(&c.hash, &txid, &vout, &c.spend, &height, &c.blockHash); err != nil {
+			return nil, fmt.Errorf("getConfirmations(%v, %v) error: %w", network, minHeight, err)
+		}
+		if err := c.outPoint.Hash.SetBytes(txid); err != nil {
+			return nil, fmt.Errorf("getConfirmations(%v, %v) error: %w", network, minHeight, err)
+		}
+		c.outPoint.Index = uint32(vout)
+		c.height = int32(height)
+		confirmations = append(confirmations, c)
+	}
+	return confirmations, rows.Err()
+}
+
+// rollbackConfirmation forgets a confirmation reorganized out of the chain
+// and reports whether the swap was reopened. A deposit goes back to the
+// mempool and its swap, if not final, to swapStateCreated until
+// updateFundedStates restores swapStateFunded. A spend is forgotten and its
+// swap, final because of it, goes back to swapStateCreated: the next spend
+// check finds the spend again if still in the chain. Final swaps stay final
+// when only a deposit was rolled back, its spend being rolled back on its
+// own.
+func rollbackConfirmation(ctx context.Context, network string, c confirmation) (bool, error) {
+
+	tx, err := pgxPool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("rollbackConfirmation(%v, %x, %v) error: %w", network, c.hash, c.outPoint, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var reopened bool
+	if c.spend {
+		_, err = tx.Exec(ctx,
+			`UPDATE swapdeposit SET spendTxid=NULL, spendPath=NULL, spendBlockHeight=NULL, spendBlockHash=NULL
+				WHERE network=$1 AND txid=$2 AND vout=$3`,
+			network, c.outPoint.Hash[:], c.outPoint.Index)
+		if err != nil {
+			return false, fmt.Errorf("rollbackConfirmation(%v, %x, %v) error: %w", network, c.hash, c.outPoint, err)
+		}
+		commandTag, err := tx.Exec(ctx,
+			`UPDATE submarineswap SET state=$3
+				WHERE network=$1 AND hash=$2 AND state IN ($4, $5, $6)`,
+			network, c.hash, swapStateCreated,
+			swapStateClaimed, swapStateClaimedByThirdParty, swapStateRefunded)
+		if err != nil {
+			return false, fmt.Errorf("rollbackConfirmation(%v, %x, %v) error: %w", network, c.hash, c.outPoint, err)
+		}
+		reopened = commandTag.RowsAffected() > 0
+	} else {
+		_, err = tx.Exec(ctx,
+			`UPDATE swapdeposit SET blockHeight=0, blockHash=NULL
+				WHERE network=$1 AND txid=$2 AND vout=$3`,
+			network, c.outPoint.Hash[:], c.outPoint.Index)
+		if err != nil {
+			return false, fmt.Errorf("rollbackConfirmation(%v, %x, %v) error: %w", network, c.hash, c.outPoint, err)
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE submarineswap SET state=$3 WHERE network=$1 AND hash=$2 AND state < $4`,
+			network, c.hash, swapStateCreated, swapStateClaimed)
+		if err != nil {
+			return false, fmt.Errorf("rollbackConfirmation(%v, %x, %v) error: %w", network, c.hash, c.outPoint, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("rollbackConfirmation(%v, %x, %v) error: %w", network, c.hash, c.outPoint, err)
+	}
+	return reopened, nil
+}
+
+// setSwapRedeemTxid records the txid of our redeem transaction and its fee,
+// so that its spends are told apart from third party claims, with the
+// preimage and the address it pays to, so that it can be replaced with a
+// higher fee. redeemTxid is the last redeem transaction, swapredeem keeps
+// them all since any of them may confirm.
+func setSwapRedeemTxid(ctx context.Context, network string, hash []byte, txid chainhash.Hash, fee btcutil.Amount, preimage []byte, redeemAddress string) error {
+
+	tx, err := pgxPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("setSwapRedeemTxid(%v, %x, %v) error: %w", network, hash, txid, err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE submarineswap SET redeemTxid=$3, preimage=$4, redeemAddress=$5
+			WHERE network=$1 AND hash=$2`,
+		network, hash, txid[:], preimage, redeemAddress)
+	if err != nil {
+		return fmt.Errorf("setSwapRedeemTxid(%v, %x, %v) error: %w", network, hash, txid, err)
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO swapredeem (network, hash, txid, fee) VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING`,
+		network, hash, txid[:], int64(fee))
+	if err != nil {
+		return fmt.Errorf("setSwapRedeemTxid(%v, %x, %v) error: %w", network, hash, txid, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("setSwapRedeemTxid(%v, %x, %v) error: %w", network, hash, txid, err)
+	}
+	return nil
+}
+
+// zeroConfDeposit is a deposit which may be counted under the zero conf
+// policy.
+type zeroConfDeposit struct {
+	chain.Utxo
+	// accepted is set once the deposit was accepted unconfirmed, so that it
+	// stays accepted until it has safe depth.
+	accepted bool
+}
+
+// getZeroConfDeposits returns the unspent deposits to the swap hash on network
+// seen only in the mempool or accepted by the zero conf policy.
+func getZeroConfDeposits(ctx context.Context, network string, hash []byte) ([]zeroConfDeposit, error) {
+
+	rows, err := pgxPool.Query(ctx,
+		`SELECT txid, vout, value, zeroConfAccepted
+			FROM swapdeposit
+			WHERE network=$1 AND hash=$2 AND (blockHeight=0 OR zeroConfAccepted) AND spendTxid IS NULL`,
+		network, hash)
+	if err != nil {
+		return nil, fmt.Errorf("getZeroConfDeposits(%v, %x) error: %w", network, hash, err)
+	}
+	defer rows.Close()
+
+	var deposits []zeroConfDeposit
+	for rows.Next() {
+		var txid []byte
+		var vout, value int64
+		var d zeroConfDeposit
+		if err := rows.Scan(&txid, &vout, &value, &d.accepted); err != nil {
+			return nil, fmt.Errorf("getZeroConfDeposits(%v, %x) error: %w", network, hash, err)
+		}
+		d.Value = btcutil.Amount(value)
+		if err := d.Hash.SetBytes(txid); err != nil {
+			return nil, fmt.Errorf("getZeroConfDeposits(%v, %x) error: %w", network, hash, err)
+		}
+		d.Index = uint32(vout)
+		deposits = append(deposits, d)
+	}
+	return deposits, rows.Err()
+}
+
+// setZeroConfAccepted records that the deposit op on network was accepted by
+// the zero conf policy.
+func setZeroConfAccepted(ctx context.Context, network string, op wire.OutPoint) error {
+
+	_, err := pgxPool.Exec(ctx,
+		`UPDATE swapdeposit SET zeroConfAccepted=true WHERE network=$1 AND txid=$2 AND vout=$3`,
+		network, op.Hash[:], op.Index)
+	if err != nil {
+		return fmt.Errorf("setZeroConfAccepted(%v, %v) error: %w", network, op, err)
+	}
+	return nil
+}
+
+// getSwapPricing returns the pricing of the swap hash on network.
+func getSwapPricing(ctx context.Context, network string, hash []byte) (swapPricing, error) {
+
+	var p swapPricing
+	var amount, serviceFee, feeRate int64
+	err := pgxPool.QueryRow(ctx,
+		`SELECT amount, serviceFee, feeRate, quoteID, COALESCE(refundAddress, '')
+			FROM submarineswap
+			WHERE network=$1 AND hash=$2`,
+		network, hash).Scan(&amount, &serviceFee, &feeRate, &p.quoteID, &p.refundAddress)
+	if err != nil {
+		return p, fmt.Errorf("getSwapPricing(%v, %x) error: %w", network, hash, err)
+	}
+	p.amount = btcutil.Amount(amount)
+	p.serviceFee = btcutil.Amount(serviceFee)
+	p.feeRate = uint64(feeRate)
+	return p, nil
+}
+
+// setSwapReconciliation records the last reconciliation of the deposits of
+// the swap hash on network.
+func setSwapReconciliation(ctx context.Context, network string, hash []byte, r reconciliation) error {
+
+	_, err := pgxPool.Exec(ctx,
+		`UPDATE submarineswap
+			SET reconciliation=$3, depositCount=$4, depositTotal=$5, redeemFee=$6, excess=$7
+			WHERE network=$1 AND hash=$2`,
+		network, hash, r.status, r.deposits, int64(r.total), int64(r.fee), int64(r.excess))
+	if err != nil {
+		return fmt.Errorf("setSwapReconciliation(%v, %x, %v) error: %w", network, hash, r, err)
+	}
+	return nil
+}
+
+func saveSwapQuote(ctx context.Context, q *swapQuote) error {
+
+	_, err := pgxPool.Exec(ctx,
+		`INSERT INTO
+	swapquote (id, network, amount, serviceFee, feeRate, minerFee, expiry)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		q.id, q.network, int64(q.amount), int64(q.serviceFee), int64(q.feeRate), int64(q.minerFee), q.expiry)
+	if err != nil {
+		return fmt.Errorf("saveSwapQuote(%x) error: %w", q.id, err)
+	}
+	return nil
+}
+
+// useSwapQuote binds the quote id on network to the swap hash and returns
+// it. A quote is used once, only until it expires and, unless amount is 0,
+// only for its amount; if it can't be used, the returned quote is nil and err
+// is nil.
+func useSwapQuote(ctx context.Context, network string, id, hash []byte, amount btcutil.Amount) (*swapQuote, error) {
+
+	q := &swapQuote{id: id, network: network}
+	var quoted, serviceFee, feeRate, minerFee int64
+	err := pgxPool.QueryRow(ctx,
+		`UPDATE swapquote SET hash=$3
+			WHERE id=$1 AND network=$2 AND hash IS NULL AND expiry > now()
+				AND ($4=0 OR amount=$4)
+			RETURNING amount, serviceFee, feeRate, minerFee, expiry`,
+		id, network, hash, int64(amount)).Scan(&quoted, &serviceFee, &feeRate, &minerFee, &q.expiry)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("useSwapQuote(%v, %x) error: %w", network, id, err)
+	}
+	q.amount = btcutil.Amount(quoted)
+	q.serviceFee = btcutil.Amount(serviceFee)
+	q.feeRate = uint64(feeRate)
+	q.minerFee = btcutil.Amount(minerFee)
+	return q, nil
+}
+
+// getCommittedAmount returns the total amount of the funded swaps, on all
+// networks, not yet redeemed.
+func getCommittedAmount(ctx context.Context) (btcutil.Amount, error) {
+
+	var amount int64
+	err := pgxPool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0)::bigint FROM submarineswap
+			WHERE state=$1 AND redeemTxid IS NULL`,
+		swapStateFunded).Scan(&amount)
+	if err != nil {
+		return 0, fmt.Errorf("getCommittedAmount() error: %w", err)
+	}
+	return btcutil.Amount(amount), nil
+}
+
+// saveCredential stores the new credential c.
+func saveCredential(ctx context.Context, c *credential) error {
+
+	var expiry *time.Time
+	if !c.expiry.IsZero() {
+		expiry = &c.expiry
+	}
+	_, err := pgxPool.Exec(ctx,
+		`INSERT INTO apicredential (id, name, secretHash, methods, maxAmount, expiry)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+		c.id, c.name, c.secretHash, c.methods, int64(c.maxAmount), expiry)
+	if err != nil {
+		return fmt.Errorf("saveCredential(%x, %v) error: %w", c.id, c.name, err)
+	}
+	return nil
+}
+
+// getCredential returns the credential id, nil if it doesn't exist.
+func getCredential(ctx context.Context, id []byte) (*credential, error) {
+
+	c := &credential{id: id}
+	var maxAmount int64
+	var expiry *time.Time
+	err := pgxPool.QueryRow(ctx,
+		`SELECT name, secretHash, methods, maxAmount, expiry, revoked
+			FROM apicredential WHERE id=$1`,
+		id).Scan(&c.name, &c.secretHash, &c.methods, &maxAmount, &expiry, &c.revoked)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getCredential(%x) error: %w", id, err)
+	}
+	c.maxAmount = btcutil.Amount(maxAmount)
+	if expiry != nil {
+		c.expiry = *expiry
+	}
+	return c, nil
+}
+
+// countUnfundedSwaps returns the number of swaps of client, on all networks,
+// waiting for a deposit. Authenticated clients are counted by credential,
+// the others by IP.
+func countUnfundedSwaps(ctx context.Context, client swapClient) (int64, error) {
+
+	var n int64
+	var err error
+	if client.credentialID != nil {
+		err = pgxPool.QueryRow(ctx,
+			`SELECT count(*) FROM submarineswap WHERE state=$1 AND credentialID=$2`,
+			swapStateCreated, client.credentialID).Scan(&n)
+	} else {
+		err = pgxPool.QueryRow(ctx,
+			`SELECT count(*) FROM submarineswap
+				WHERE state=$1 AND credentialID IS NULL AND clientIP=$2`,
+			swapStateCreated, client.ip).Scan(&n)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("countUnfundedSwaps(%x, %v) error: %w", client.credentialID, client.ip, err)
+	}
+	return n, nil
+}
+
+// getExpirableSwaps returns the swaps on network created before
+// createdBefore still in swapStateCreated and without any recorded deposit,
+// even unconfirmed.
+func getExpirableSwaps(ctx context.Context, network string, createdBefore time.Time) ([]openSwap, error) {
+
+	rows, err := pgxPool.Query(ctx,
+		`SELECT hash, script, COALESCE(address, ''), created
+			FROM submarineswap s
+			WHERE network=$1 AND state=$3 AND created < $2
+				AND NOT EXISTS (SELECT 1 FROM swapdeposit d WHERE d.network=s.network AND d.hash=s.hash)`,
+		network, createdBefore, swapStateCreated)
+	if err != nil {
+		return nil, fmt.Errorf("getExpirableSwaps(%v, %v) error: %w", network, createdBefore, err)
+	}
+	defer rows.Close()
+
+	var swaps []openSwap
+	for rows.Next() {
+		var s openSwap
+		if err := rows.Scan(&s.hash, &s.script, &s.address, &s.created); err != nil {
+			return nil, fmt.Errorf("getExpirableSwaps(%v, %v) error: %w", network, createdBefore, err)
+		}
+		swaps = append(swaps, s)
+	}
+	return swaps, rows.Err()
+}
+
+// expireSwap moves the swap hash on network to swapStateExpired if it is
+// still in swapStateCreated without any recorded deposit, and reports whether
+// it did.
+func expireSwap(ctx context.Context, network string, hash []byte) (bool, error) {
+
+	commandTag, err := pgxPool.Exec(ctx,
+		`UPDATE submarineswap s SET state=$3
+			WHERE network=$1 AND hash=$2 AND state=$4
+				AND NOT EXISTS (SELECT 1 FROM swapdeposit d WHERE d.network=s.network AND d.hash=s.hash)`,
+		network, hash, swapStateExpired, swapStateCreated)
+	if err != nil {
+		return false, fmt.Errorf("expireSwap(%v, %x) error: %w", network, hash, err)
+	}
+	return commandTag.RowsAffected() > 0, nil
+}
+
+// liabilities sums up what the swaps on a network not in a final state
+// represent.
+type liabilities struct {
+	openSwaps int64
+	// committed is the amount of the funded swaps not yet redeemed.
+	committed btcutil.Amount
+	// deposits is the value of the unspent deposits.
+	deposits btcutil.Amount
+}
+
+// getLiabilities returns the liabilities of each network.
+func getLiabilities(ctx context.Context) (map[string]liabilities, error) {
+
+	rows, err := pgxPool.Query(ctx,
+		`SELECT s.network, count(*),
+				COALESCE(SUM(s.amount) FILTER (WHERE s.state=$2 AND s.redeemTxid IS NULL), 0)::bigint,
+				COALESCE(SUM((SELECT SUM(d.value) FROM swapdeposit d
+					WHERE d.network=s.network AND d.hash=s.hash AND d.spendTxid IS NULL)), 0)::bigint
+			FROM submarineswap s WHERE s.state<$1
+			GROUP BY s.network`,
+		swapStateClaimed, swapStateFunded)
+	if err != nil {
+		return nil, fmt.Errorf("getLiabilities() error: %w", err)
+	}
+	defer rows.Close()
+
+	l := make(map[string]liabilities)
+	for rows.Next() {
+		var network string
+		var openSwaps, committed, deposits int64
+		if err := rows.Scan(&network, &openSwaps, &committed, &deposits); err != nil {
+			return nil, fmt.Errorf("getLiabilities() error: %w", err)
+		}
+		l[network] = liabilities{
+			openSwaps: openSwaps,
+			committed: btcutil.Amount(committed),
+			deposits:  btcutil.Amount(deposits),
+		}
+	}
+	return l, rows.Err()
+}
+
+// swapRecord is a stored swap, without its key.
+type swapRecord struct {
+	network        string
+	hash           []byte
+	typ            swapscript.Type
+	lockHeight     int64
+	script         []byte
+	address        string
+	state          swapState
+	pricing        swapPricing
+	reconciliation reconciliationStatus
+	redeemTxid     []byte
+	created        time.Time
+	credentialID   []byte
+}
+
+const swapRecordColumns = `network, hash, swapType, lockHeight, script, COALESCE(address, ''), state,
+	amount, serviceFee, feeRate, quoteID, COALESCE(refundAddress, ''), reconciliation,
+	redeemTxid, created, credentialID`
+
+func scanSwapRecord(row pgx.Row) (*swapRecord, error) {
+	var s swapRecord
+	var amount, serviceFee, feeRate int64
+	err := row.Scan(&s.network, &s.hash, &s.typ, &s.lockHeight, &s.script, &s.address, &s.state,
+		&amount, &serviceFee, &feeRate, &s.pricing.quoteID, &s.pricing.refundAddress, &s.reconciliation,
+		&s.redeemTxid, &s.created, &s.credentialID)
+	if err != nil {
+		return nil, err
+	}
+	s.pricing.amount = btcutil.Amount(amount)
+	s.pricing.serviceFee = btcutil.Amount(serviceFee)
+	s.pricing.feeRate = uint64(feeRate)
+	return &s, nil
+}
+
+// swapFilter selects swaps. Its zero value selects them all.
+type swapFilter struct {
+	// network is empty for all networks.
+	network string
+	// state is nil for all states.
+	state *swapState
+	// search matches the swap address, the refund address or the beginning
+	// of the hash in hex.
+	search string
+	// createdAfter and createdBefore bound the creation time, zero for no
+	// bound. createdBefore is excluded.
+	createdAfter  time.Time
+	createdBefore time.Time
+	// limit is 0 for all swaps.
+	limit  int
+	offset int
+}
+
+// listSwaps returns the swaps selected by f, newest first.
+func listSwaps(ctx context.Context, f swapFilter) ([]*swapRecord, error) {
+
+	var createdBefore interface{}
+	if !f.createdBefore.IsZero() {
+		createdBefore = f.createdBefore
+	}
+	rows, err := pgxPool.Query(ctx,
+		`SELECT `+swapRecordColumns+` FROM submarineswap
+			WHERE ($1='' OR network=$1) AND ($2::smallint IS NULL OR state=$2)
+			AND ($3='' OR address=$3 OR refundAddress=$3 OR encode(hash, 'hex') LIKE lower($3) || '%')
+			AND created >= $4 AND ($5::timestamptz IS NULL OR created < $5)
+			ORDER BY created DESC LIMIT NULLIF($6, 0) OFFSET $7`,
+		f.network, f.state, f.search, f.createdAfter, createdBefore, f.limit, f.offset)
+	if err != nil {
+		return nil, fmt.Errorf("listSwaps(%v, %v, %v) error: %w", f.network, f.state, f.search, err)
+	}
+	defer rows.Close()
+
+	var swaps []*swapRecord
+	for rows.Next() {
+		s, err := scanSwapRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listSwaps(%v, %v, %v) error: %w", f.network, f.state, f.search, err)
+		}
+		swaps = append(swaps, s)
+	}
+	return swaps, rows.Err()
+}
+
+// getSwap returns the swap hash on network, nil if it doesn't exist.
+func getSwap(ctx context.Context, network string, hash []byte) (*swapRecord, error) {
+
+	s, err := scanSwapRecord(pgxPool.QueryRow(ctx,
+		`SELECT `+swapRecordColumns+` FROM submarineswap WHERE network=$1 AND hash=$2`,
+		network, hash))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getSwap(%v, %x) error: %w", network, hash, err)
+	}
+	return s, nil
+}
+
+// swapDeposit is a recorded deposit of a swap.
+type swapDeposit struct {
+	outPoint wire.OutPoint
+	value    btcutil.Amount
+	// blockHeight is 0 for deposits seen only in the mempool.
+	blockHeight int32
+	// spendTxid is nil while the deposit is unspent.
+	spendTxid []byte
+	spendPath swapscript.Path
+}
+
+// getSwapDeposits returns the deposits to the swap hash on network.
+func getSwapDeposits(ctx context.Context, network string, hash []byte) ([]swapDeposit, error) {
+
+	rows, err := pgxPool.Query(ctx,
+		`SELECT txid, vout, value, blockHeight, spendTxid, COALESCE(spendPath, 0)
+			FROM swapdeposit WHERE network=$1 AND hash=$2
+			ORDER BY blockHeight, txid, vout`,
+		network, hash)
+	if err != nil {
+		return nil, fmt.Errorf("getSwapDeposits(%v, %x) error: %w", network, hash, err)
+	}
+	defer rows.Close()
+
+	var deposits []swapDeposit
+	for rows.Next() {
+		var d swapDeposit
+		var txid []byte
+		var vout, value, blockHeight int64
+		err := rows.Scan(&txid, &vout, &value, &blockHeight, &d.spendTxid, &d.spendPath)
+		if err != nil {
+			return nil, fmt.Errorf("getSwapDeposits(%v, %x) error: %w", network, hash, err)
+		}
+		h, err := chainhash.NewHash(txid)
+		if err != nil {
+			return nil, fmt.Errorf("getSwapDeposits(%v, %x) error: %w", network, hash, err)
+		}
+		d.outPoint = *wire.NewOutPoint(h, uint32(vout))
+		d.value = btcutil.Amount(value)
+		d.blockHeight = int32(blockHeight)
+		deposits = append(deposits, d)
+	}
+	return deposits, rows.Err()
+}
+
+// getRedeem returns the preimage and the address of the last redeem
+// transaction of the swap hash on network, nil and empty if there is none.
+func getRedeem(ctx context.Context, network string, hash []byte) (preimage []byte, redeemAddress string, err error) {
+
+	err = pgxPool.QueryRow(ctx,
+		`SELECT preimage, COALESCE(redeemAddress, '') FROM submarineswap
+			WHERE network=$1 AND hash=$2 AND redeemTxid IS NOT NULL`,
+		network, hash).Scan(&preimage, &redeemAddress)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("getRedeem(%v, %x) error: %w", network, hash, err)
+	}
+	return preimage, redeemAddress, nil
+}
+
+// setSwapState moves the swap hash on network from state from to state to.
+// It returns false if the swap isn't in state from.
+func setSwapState(ctx context.Context, network string, hash []byte, from, to swapState) (bool, error) {
+
+	commandTag, err := pgxPool.Exec(ctx,
+		`UPDATE submarineswap SET state=$4 WHERE network=$1 AND hash=$2 AND state=$3`,
+		network, hash, from, to)
+	if err != nil {
+		return false, fmt.Errorf("setSwapState(%v, %x, %v, %v) error: %w", network, hash, from, to, err)
+	}
+	return commandTag.RowsAffected() == 1, nil
 }
@@ -1,51 +1,51 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/x509"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net"
 	"os"
+	"os/signal"
 	"strings"
-	"swapper/mempoolspace"
+	"swapper/chain"
+	"swapper/logging"
+	"swapper/submarineswaprpc"
+	"swapper/swapscript"
+	"syscall"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
-	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 const (
 	defaultLockHeight      = 288
 	redeemWitnessInputSize = 1 + 1 + 73 + 1 + 32 + 1 + 100
+	refundWitnessInputSize = 1 + 1 + 73 + 1 + 1 + 100
+	probingHashPrefix      = "probing-01:"
 )
 
-func generateSubmarineSwapScript(swapperPubKey, payerPubKey, hash []byte, lockHeight int64) ([]byte, error) {
-	builder := txscript.NewScriptBuilder()
-
-	builder.AddOp(txscript.OP_HASH160)
-	builder.AddData(input.Ripemd160H(hash))
-	builder.AddOp(txscript.OP_EQUAL) // Leaves 0P1 (true) on the stack if preimage matches
-	builder.AddOp(txscript.OP_IF)
-	builder.AddData(swapperPubKey) // Path taken if preimage matches
-	builder.AddOp(txscript.OP_ELSE)
-	builder.AddInt64(lockHeight)
-	builder.AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
-	builder.AddOp(txscript.OP_DROP)
-	builder.AddData(payerPubKey) // Refund back to payer
-	builder.AddOp(txscript.OP_ENDIF)
-	builder.AddOp(txscript.OP_CHECKSIG)
-
-	return builder.Script()
+// probingHash returns sha256(probingHashPrefix || hash). It lets a client ask
+// whether a swap exists without revealing the payment hash.
+func probingHash(hash []byte) []byte {
+	h := sha256.Sum256(append([]byte(probingHashPrefix), hash...))
+	return h[:]
 }
 
-func NewSubmarineSwap(net *chaincfg.Params, pubKey, hash []byte) (address btcutil.Address, script, swapperPubKey []byte, lockHeight int64, err error) {
+func NewSubmarineSwap(ctx context.Context, net *chaincfg.Params, pubKey, hash []byte, typ swapscript.Type, requestedLockHeight int64, amount int64, refundAddress string, quoteID []byte) (address btcutil.Address, script, swapperPubKey []byte, lockHeight int64, err error) {
 
 	if len(pubKey) != btcec.PubKeyBytesLenCompressed {
 		err = errors.New("pubKey not valid")
@@ -56,12 +56,84 @@ func NewSubmarineSwap(net *chaincfg.Params, pubKey, hash []byte) (address btcuti
 		err = errors.New("hash not valid")
 		return
 	}
+
+	if amount < 0 {
+		err = errors.New("amount not valid")
+		return
+	}
+	if refundAddress != "" {
+		var refund btcutil.Address
+		refund, err = btcutil.DecodeAddress(refundAddress, net)
+		if err != nil || !refund.IsForNet(net) {
+			err = errors.New("refund address not valid")
+			return
+		}
+	}
+
+	switch typ {
+	case swapscript.CSV:
+		lockHeight, err = chooseLockHeight(requestedLockHeight)
+	case swapscript.CLTV:
+		var c chain.Backend
+		c, err = chainClient(net)
+		if err != nil {
+			return
+		}
+		var currentHeight uint32
+		currentHeight, err = c.CurrentHeight(ctx)
+		if err != nil {
+			return
+		}
+		lockHeight, err = chooseLockTime(requestedLockHeight, int64(currentHeight))
+	default:
+		err = fmt.Errorf("unknown swap type %v", typ)
+	}
+	if err != nil {
+		return
+	}
+
 	//Need to check that the hash doesn't already exists in our db
-	_, _, _, _, errGet := getSwapperSubmarineData(hash)
-	if errGet == nil {
+	match, err := matchSwapperSubmarineHash(ctx, net.Name, hash)
+	if err != nil {
+		return
+	}
+	if match != hashMatchNone {
 		err = errors.New("Hash already exists")
 		return
 	}
+
+	pricing := swapPricing{amount: btcutil.Amount(amount), refundAddress: refundAddress}
+	if len(quoteID) > 0 {
+		var q *swapQuote
+		q, err = useSwapQuote(ctx, net.Name, quoteID, hash, pricing.amount)
+		if err != nil {
+			return
+		}
+		if q == nil {
+			err = errors.New("quote not valid")
+			return
+		}
+		pricing.amount = q.amount
+		pricing.serviceFee = q.serviceFee
+		pricing.feeRate = q.feeRate
+		pricing.quoteID = q.id
+	} else if amount > 0 {
+		pricing.serviceFee = serviceFee(pricing.amount)
+	}
+	err = credentialFromContext(ctx).checkAmount(pricing.amount)
+	if err != nil {
+		return
+	}
+	client := clientFromContext(ctx)
+	err = checkUnfundedSwaps(ctx, client)
+	if err != nil {
+		return
+	}
+	err = admitSwap(ctx, pricing.amount)
+	if err != nil {
+		return
+	}
+
 	//Create swapperKey and swapperPubKey
 	key, err := btcec.NewPrivateKey(btcec.S256())
 	if err != nil {
@@ -69,44 +141,81 @@ func NewSubmarineSwap(net *chaincfg.Params, pubKey, hash []byte) (address btcuti
 	}
 	swapperKey := key.Serialize()
 	swapperPubKey = key.PubKey().SerializeCompressed()
-	lockHeight = defaultLockHeight
 
 	//Create the script
-	script, err = generateSubmarineSwapScript(swapperPubKey, pubKey, hash, defaultLockHeight)
+	script, err = swapscript.New(typ, swapperPubKey, pubKey, hash, lockHeight)
 	if err != nil {
 		return
 	}
 
-	address, err = newAddressWitnessScriptHash(script, net)
+	address, err = swapscript.Address(script, net)
 	if err != nil {
 		return
 	}
 
 	//Need to save the data into postgres
-	err = saveSwapperSubmarineData(net.ScriptHashAddrID, hash, lockHeight, swapperKey, script)
+	err = saveSwapperSubmarineData(ctx, net.Name, net.ScriptHashAddrID, hash, typ, lockHeight, swapperKey, script, address.EncodeAddress(), pricing, client)
+	if err != nil {
+		return
+	}
+	swapsCreated.WithLabelValues(net.Name).Inc()
 
+	trackSwapAddress(net, address, time.Now())
 	return
 }
-func newAddressWitnessScriptHash(script []byte, net *chaincfg.Params) (*btcutil.AddressWitnessScriptHash, error) {
-	witnessProg := sha256.Sum256(script)
-	return btcutil.NewAddressWitnessScriptHash(witnessProg[:], net)
-}
-func redeemFees(net *chaincfg.Params, hash []byte, feePerKw chainfee.SatPerKWeight) (btcutil.Amount, error) {
-	c := &mempoolspace.Client{baseUrl: "https://mempool.space/api"}
-	utxos, err := c.GetUtxos(hash)
+func redeemFees(ctx context.Context, net *chaincfg.Params, hash []byte, feePerKw chainfee.SatPerKWeight) (btcutil.Amount, error) {
+	c, err := chainClient(net)
+	if err != nil {
+		return 0, err
+	}
+	_, _, _, script, err := getSwapperSubmarineData(ctx, net.Name, hash)
+	if err != nil {
+		return 0, err
+	}
+	if script == nil {
+		return 0, errors.New("unknown swap")
+	}
+	pricing, err := getSwapPricing(ctx, net.Name, hash)
+	if err != nil {
+		return 0, err
+	}
+	refundAddress, err := pricing.refund(net)
+	if err != nil {
+		return 0, err
+	}
+	address, err := swapscript.Address(script, net)
+	if err != nil {
+		return 0, err
+	}
+	utxos, err := c.GetUtxos(ctx, address)
 	if err != nil {
 		return 0, err
 	}
+	currentHeight, err := c.CurrentHeight(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// The payer could refund the deposits of a CLTV swap before the claim
+	// confirms.
+	if err := checkRefundDistance(script, int64(currentHeight)); err != nil {
+		return 0, err
+	}
+	// The invoice is paid once the fees are known: only count the deposits
+	// which can't be reorganized out anymore, and those accepted by the zero
+	// conf policy.
+	zeroConfs, err := zeroConfUtxos(ctx, net, hash, utxos)
+	if err != nil {
+		return 0, err
+	}
+	utxos = addUtxos(safeUtxos(utxos, int32(currentHeight)), zeroConfs)
 	if len(utxos) == 0 {
-		return 0, errors.New("no utxo")
+		return 0, errors.New("no utxo at safe depth")
 	}
 
 	redeemTx := wire.NewMsgTx(1)
 
-	// Add the inputs without the witness and calculate the amount to redeem
-	var amount btcutil.Amount
+	// Add the inputs without the witness
 	for _, utxo := range utxos {
-		amount += utxo.Value
 		txIn := wire.NewTxIn(&utxo.OutPoint, nil, nil)
 		txIn.Sequence = 0
 		redeemTx.AddTxIn(txIn)
@@ -129,40 +238,83 @@ func redeemFees(net *chaincfg.Params, hash []byte, feePerKw chainfee.SatPerKWeig
 	txOut := wire.TxOut{PkScript: redeemScript}
 	redeemTx.AddTxOut(&txOut)
 
-	currentHeight, err := c.CurrentHeight()
+	redeemTx.LockTime = uint32(currentHeight)
+
+	// Only pay the invoice if the deposits cover it with the fees.
+	r, err := reconcileRedeemTx(redeemTx, utxos, pricing.due(), refundAddress, feePerKw, feePerKw)
 	if err != nil {
 		return 0, err
 	}
-	redeemTx.LockTime = uint32(currentHeight)
-
-	// Calcluate the weight and the fee
-	weight := 4*redeemTx.SerializeSizeStripped() + redeemWitnessInputSize*len(redeemTx.TxIn)
-	// Adjust the amount in the txout
-	return feePerKw.FeeForWeight(int64(weight)), nil
+	if err := setSwapReconciliation(ctx, net.Name, hash, r); err != nil {
+		return 0, err
+	}
+	if r.status == reconciliationUnderpaid {
+		return 0, fmt.Errorf("swap underpaid: %v", r)
+	}
+	return r.fee, nil
 }
 
 // Redeem
-func redeem(net *chaincfg.Params, preimage []byte, redeemAddress btcutil.Address, feePerKw chainfee.SatPerKWeight) (*wire.MsgTx, error) {
-	c := &mempoolspace.Client{baseUrl: "https://mempool.space/api"}
+func redeem(ctx context.Context, net *chaincfg.Params, preimage []byte, redeemAddress btcutil.Address, feePerKw chainfee.SatPerKWeight) (*wire.MsgTx, error) {
+	c, err := chainClient(net)
+	if err != nil {
+		return nil, err
+	}
 	hash := sha256.Sum256(preimage)
-	_, serviceKey, script, err := getSwapperSubmarineData(net.ScriptHashAddrID, hash[:])
+	_, _, _, script, err := getSwapperSubmarineData(ctx, net.Name, hash[:])
 	if err != nil {
 		return nil, err
 	}
-	utxos, err := c.GetUtxos(hash)
+	if script == nil {
+		return nil, errors.New("unknown swap")
+	}
+	address, err := swapscript.Address(script, net)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	utxos, err := c.GetUtxos(ctx, address)
+	if err != nil {
+		return nil, err
 	}
+	zeroConfs, err := zeroConfUtxos(ctx, net, hash[:], utxos)
+	if err != nil {
+		return nil, err
+	}
+	return redeemUtxos(ctx, net, preimage, redeemAddress, addUtxos(utxos, zeroConfs), feePerKw)
+}
+
+// redeemUtxos signs and broadcasts the transaction spending utxos of the swap
+// of preimage through the claim path to redeemAddress. It replaces a previous
+// redeem transaction spending the same utxos if feePerKw is higher.
+func redeemUtxos(ctx context.Context, net *chaincfg.Params, preimage []byte, redeemAddress btcutil.Address, utxos []chain.Utxo, feePerKw chainfee.SatPerKWeight) (*wire.MsgTx, error) {
 	if len(utxos) == 0 {
-		return 0, errors.New("no utxo")
+		return nil, errors.New("no utxo")
+	}
+	c, err := chainClient(net)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(preimage)
+	_, _, serviceKey, script, err := getSwapperSubmarineData(ctx, net.Name, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	if script == nil {
+		return nil, errors.New("unknown swap")
+	}
+	pricing, err := getSwapPricing(ctx, net.Name, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	refundAddress, err := pricing.refund(net)
+	if err != nil {
+		return nil, err
 	}
 
 	redeemTx := wire.NewMsgTx(1)
 
-	// Add the inputs without the witness and calculate the amount to redeem
-	var amount btcutil.Amount
+	// Add the inputs without the witness
 	for _, utxo := range utxos {
-		amount += utxo.Value
 		txIn := wire.NewTxIn(&utxo.OutPoint, nil, nil)
 		txIn.Sequence = 0
 		redeemTx.AddTxIn(txIn)
@@ -176,16 +328,24 @@ func redeem(net *chaincfg.Params, preimage []byte, redeemAddress btcutil.Address
 	txOut := wire.TxOut{PkScript: redeemScript}
 	redeemTx.AddTxOut(&txOut)
 
-	currentHeight, err := c.CurrentHeight()
+	currentHeight, err := c.CurrentHeight(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	redeemTx.LockTime = uint32(currentHeight)
 
-	// Calcluate the weight and the fee
-	weight := 4*redeemTx.SerializeSizeStripped() + redeemWitnessInputSize*len(redeemTx.TxIn)
-	// Adjust the amount in the txout
-	redeemTx.TxOut[0].Value = int64(amount - feePerKw.FeeForWeight(int64(weight)))
+	// The invoice is already paid: redeem even if underpaid. The payer
+	// keeps the fee rate of the quote, the swapper pays the difference.
+	r, err := reconcileRedeemTx(redeemTx, utxos, pricing.due(), refundAddress, pricing.chargedFeePerKw(feePerKw), feePerKw)
+	if err != nil {
+		return nil, err
+	}
+	if err := setSwapReconciliation(ctx, net.Name, hash[:], r); err != nil {
+		return nil, err
+	}
+	if redeemTx.TxOut[0].Value <= 0 {
+		return nil, fmt.Errorf("nothing to redeem: %v", r)
+	}
 
 	sigHashes := txscript.NewTxSigHashes(redeemTx)
 	privateKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), serviceKey)
@@ -197,7 +357,12 @@ func redeem(net *chaincfg.Params, preimage []byte, redeemAddress btcutil.Address
 		redeemTx.TxIn[idx].Witness = [][]byte{scriptSig, preimage, script}
 	}
 
-	err = c.BroadcastTransaction(redeemTx)
+	// Recorded before broadcasting so that the spend watcher recognizes it.
+	err = setSwapRedeemTxid(ctx, net.Name, hash[:], redeemTx.TxHash(), redeemTxFee(redeemTx, feePerKw), preimage, redeemAddress.EncodeAddress())
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.BroadcastTransaction(ctx, redeemTx)
 	if err != nil {
 		return nil, err
 	}
@@ -205,29 +370,96 @@ func redeem(net *chaincfg.Params, preimage []byte, redeemAddress btcutil.Address
 	return redeemTx, nil
 }
 
-func subSwapServiceRedeemFees(ActiveNetParams *chaincfg.Params, hash []byte) (int64, error) {
-	c := &mempoolspace.Client{baseUrl: "https://mempool.space/api"}
-	fee, err := c.RecommendedFee()
-	feePerKw, err := chainfee.SatPerKVByte(fee * 1000).FeePerKWeight()
+// refundTx builds the unsigned transaction sending the swap utxos back to
+// refundAddress through the timelocked path of the script. The claim path
+// doesn't depend on the swap type, so redeem works for both. The inputs have
+// no witness: the payer signs each of them and sets <sig> <> <script>, the
+// empty element selecting the refund branch.
+func refundTx(typ swapscript.Type, lockHeight int64, utxos []chain.Utxo, refundAddress btcutil.Address, feePerKw chainfee.SatPerKWeight) (*wire.MsgTx, error) {
+	if len(utxos) == 0 {
+		return nil, errors.New("no utxo")
+	}
+
+	// Version 2 is needed for OP_CHECKSEQUENCEVERIFY.
+	tx := wire.NewMsgTx(2)
+
+	var amount btcutil.Amount
+	for _, utxo := range utxos {
+		amount += utxo.Value
+		txIn := wire.NewTxIn(&utxo.OutPoint, nil, nil)
+		switch typ {
+		case swapscript.CSV:
+			txIn.Sequence = uint32(lockHeight)
+		case swapscript.CLTV:
+			// A final sequence would disable the locktime check.
+			txIn.Sequence = wire.MaxTxInSequenceNum - 1
+		default:
+			return nil, fmt.Errorf("unknown swap type %v", typ)
+		}
+		tx.AddTxIn(txIn)
+	}
+	if typ == swapscript.CLTV {
+		tx.LockTime = uint32(lockHeight)
+	}
+
+	refundScript, err := txscript.PayToAddrScript(refundAddress)
+	if err != nil {
+		return nil, err
+	}
+	tx.AddTxOut(&wire.TxOut{PkScript: refundScript})
+
+	weight := 4*tx.SerializeSizeStripped() + refundWitnessInputSize*len(tx.TxIn)
+	fee := feePerKw.FeeForWeight(int64(weight))
+	if amount <= fee {
+		return nil, fmt.Errorf("amount %v doesn't cover the fee %v", amount, fee)
+	}
+	tx.TxOut[0].Value = int64(amount - fee)
+
+	return tx, nil
+}
+
+// recommendedFeePerKw returns the fee rate recommended by the chain backend
+// of net.
+func recommendedFeePerKw(ctx context.Context, net *chaincfg.Params) (chainfee.SatPerKWeight, error) {
+	c, err := chainClient(net)
 	if err != nil {
 		return 0, err
 	}
+	fee, err := c.RecommendedFee(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return chainfee.SatPerKVByte(fee * 1000).FeePerKWeight(), nil
+}
 
-	amount, err := redeemFees(ActiveNetParams, hash, feePerKw)
+func subSwapServiceRedeemFees(ctx context.Context, ActiveNetParams *chaincfg.Params, hash []byte) (int64, error) {
+	// A quoted swap is charged the quoted fee rate.
+	pricing, err := getSwapPricing(ctx, ActiveNetParams.Name, hash)
+	if err != nil {
+		return 0, err
+	}
+	feePerKw := chainfee.SatPerKVByte(pricing.feeRate * 1000).FeePerKWeight()
+	if pricing.feeRate == 0 {
+		feePerKw, err = recommendedFeePerKw(ctx, ActiveNetParams)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	amount, err := redeemFees(ctx, ActiveNetParams, hash, feePerKw)
 
 	if err != nil {
 		return 0, err
 	}
 	return int64(amount), nil
 }
-func subSwapServiceRedeem(ActiveNetParams *chaincfg.Params, hash []byte, preimage []byte, redeemAddress btcutil.Address) ([]byte, error) {
-	c := &mempoolspace.Client{baseUrl: "https://mempool.space/api"}
-	fee, err := c.RecommendedFee()
-	feePerKw, err := chainfee.SatPerKVByte(fee * 1000).FeePerKWeight()
+func subSwapServiceRedeem(ctx context.Context, ActiveNetParams *chaincfg.Params, hash []byte, preimage []byte, redeemAddress btcutil.Address) (string, error) {
+	feePerKw, err := recommendedFeePerKw(ctx, ActiveNetParams)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	tx, err := redeem(
+		ctx,
 		ActiveNetParams,
 		preimage,
 		redeemAddress,
@@ -235,43 +467,176 @@ func subSwapServiceRedeem(ActiveNetParams *chaincfg.Params, hash []byte, preimag
 	)
 
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	log.Infof("[subswapserviceredeem] txid: %v", tx.TxHash().String())
+	slog.Info("swap redeemed", "network", ActiveNetParams.Name, "hash", hash, "txid", tx.TxHash())
 	return tx.TxHash().String(), nil
 }
 
-func main() {
+// swapper implements submarineswaprpc.Swapper.
+type swapper struct{}
 
-	err := pgConnect()
+func (swapper) NewSubmarineSwap(ctx context.Context, network string, pubKey, hash []byte, typ submarineswaprpc.SwapType, lockHeight int64, amount int64, refundAddress string, quoteID []byte) (btcutil.Address, []byte, []byte, int64, error) {
+	n, err := getNetwork(network)
 	if err != nil {
-		log.Fatalf("pgConnect() error: %v", err)
+		return nil, nil, nil, 0, err
 	}
+	return NewSubmarineSwap(ctx, n.params, pubKey, hash, swapscript.Type(typ), lockHeight, amount, refundAddress, quoteID)
+}
 
-	address := os.Getenv("LISTEN_ADDRESS")
-	var lis net.Listener
+func (swapper) GetLimits(ctx context.Context, network string) (int64, int64, error) {
+	if _, err := getNetwork(network); err != nil {
+		return 0, 0, err
+	}
+	min, max, err := swapLimits(ctx)
+	return int64(min), int64(max), err
+}
 
-	lis, err = net.Listen("tcp", address)
+func (swapper) GetQuote(ctx context.Context, network string, amount int64) (*submarineswaprpc.GetQuoteResponse, error) {
+	n, err := getNetwork(network)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return nil, err
+	}
+	if err := credentialFromContext(ctx).checkAmount(btcutil.Amount(amount)); err != nil {
+		return nil, err
+	}
+	q, err := newQuote(ctx, n.params, btcutil.Amount(amount))
+	if err != nil {
+		return nil, err
+	}
+	return &submarineswaprpc.GetQuoteResponse{
+		QuoteId:    q.id,
+		Amount:     int64(q.amount),
+		ServiceFee: int64(q.serviceFee),
+		FeeRate:    int64(q.feeRate),
+		MinerFee:   int64(q.minerFee),
+		Total:      int64(q.total()),
+		Expiry:     q.expiry.Unix(),
+	}, nil
+}
+
+func (swapper) ProbingHashExists(ctx context.Context, network string, probingHash []byte) (bool, error) {
+	n, err := getNetwork(network)
+	if err != nil {
+		return false, err
+	}
+	return probingHashExists(ctx, n.params.Name, probingHash)
+}
+
+func (swapper) SwapScript(ctx context.Context, network string, hash []byte) ([]byte, error) {
+	n, err := getNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, script, err := getSwapperSubmarineData(ctx, n.params.Name, hash)
+	return script, err
+}
+
+// macaroonCredential sends a hex encoded lnd macaroon with each call.
+type macaroonCredential string
+
+func (m macaroonCredential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"macaroon": string(m)}, nil
+}
+
+func (m macaroonCredential) RequireTransportSecurity() bool {
+	return true
+}
+
+func main() {
+
+	if err := logging.Setup(); err != nil {
+		logging.Fatal("logging.Setup() failed", "error", err)
+	}
+
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		logging.Fatal("setupTracing() failed", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	err = pgConnect()
+	if err != nil {
+		logging.Fatal("pgConnect() failed", "error", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "issue-credential" {
+		if err := issueCredentialCommand(os.Args[2:]); err != nil {
+			logging.Fatal("issue-credential failed", "error", err)
+		}
+		return
 	}
 
 	// Creds file to connect to gRPC
 	cp := x509.NewCertPool()
 	if !cp.AppendCertsFromPEM([]byte(strings.Replace(os.Getenv("CERT"), "\\n", "\n", -1))) {
-		log.Fatalf("credentials: failed to append certificates")
+		logging.Fatal("credentials: failed to append certificates")
 	}
 	creds := credentials.NewClientTLSFromCert(cp, "")
 
-	conn, err := grpc.Dial(os.Getenv("ADDRESS"), grpc.WithTransportCredentials(creds))
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+	if mac := os.Getenv("MACAROON"); mac != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(macaroonCredential(mac)))
+	}
+	conn, err := grpc.Dial(os.Getenv("ADDRESS"), dialOpts...)
 	if err != nil {
-		log.Fatalf("Failed to connect to gRPC: %v", err)
+		logging.Fatal("failed to connect to lnd", "error", err)
 	}
 	defer conn.Close()
-	var opts []grpc.ServerOption
+	lightning = lnrpc.NewLightningClient(conn)
+
+	err = loadNetworks(conn)
+	if err != nil {
+		logging.Fatal("loadNetworks() failed", "error", err)
+	}
+
+	err = loadConfig()
+	if err != nil {
+		logging.Fatal("loadConfig() failed", "error", err)
+	}
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			reloadConfig()
+		}
+	}()
+
+	for _, n := range networks {
+		go watchNetwork(context.Background(), n)
+	}
+
+	healthChecker := newHealthChecker()
+	go healthChecker.run(context.Background())
+	serveHTTP(healthChecker)
+
+	err = serveAdmin()
+	if err != nil {
+		logging.Fatal("serveAdmin() failed", "error", err)
+	}
+
+	address := os.Getenv("LISTEN_ADDRESS")
+	var lis net.Listener
+
+	lis, err = net.Listen("tcp", address)
+	if err != nil {
+		logging.Fatal("failed to listen", "address", address, "error", err)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(authInterceptor, rateLimitInterceptor),
+	}
 	s := grpc.NewServer(opts...)
+	submarineswaprpc.RegisterSubmarineSwapperServer(s, &submarineswaprpc.Server{
+		Swapper: swapper{},
+	})
+	healthpb.RegisterHealthServer(s, healthChecker.grpcHealth)
 
 	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+		logging.Fatal("failed to serve", "error", err)
 	}
 }
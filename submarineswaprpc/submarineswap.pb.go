@@ -4,7 +4,7 @@
 // 	protoc        v3.21.2
 // source: submarineswap.proto
 
-package __
+package submarineswaprpc
 
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
@@ -20,6 +20,56 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type SwapType int32
+
+const (
+	// Refund path locked with OP_CHECKSEQUENCEVERIFY, relative to the
+	// deposit confirmation.
+	SwapType_CSV SwapType = 0
+	// Refund path locked with OP_CHECKLOCKTIMEVERIFY at an absolute block
+	// height.
+	SwapType_CLTV SwapType = 1
+)
+
+// Enum value maps for SwapType.
+var (
+	SwapType_name = map[int32]string{
+		0: "CSV",
+		1: "CLTV",
+	}
+	SwapType_value = map[string]int32{
+		"CSV":  0,
+		"CLTV": 1,
+	}
+)
+
+func (x SwapType) Enum() *SwapType {
+	p := new(SwapType)
+	*p = x
+	return p
+}
+
+func (x SwapType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SwapType) Descriptor() protoreflect.EnumDescriptor {
+	return file_submarineswap_proto_enumTypes[0].Descriptor()
+}
+
+func (SwapType) Type() protoreflect.EnumType {
+	return &file_submarineswap_proto_enumTypes[0]
+}
+
+func (x SwapType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SwapType.Descriptor instead.
+func (SwapType) EnumDescriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{0}
+}
+
 type SubSwapServiceInitRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -27,6 +77,22 @@ type SubSwapServiceInitRequest struct {
 
 	Hash   []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
 	Pubkey []byte `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	// mainnet, testnet, signet or regtest. Empty selects the default network.
+	Network string `protobuf:"bytes,3,opt,name=network,proto3" json:"network,omitempty"`
+	// Requested CSV delay in blocks, or absolute refund height for CLTV
+	// swaps. Zero lets the server choose; other values must be within the
+	// server bounds or the request is rejected.
+	LockHeight int64    `protobuf:"varint,4,opt,name=lock_height,proto3" json:"lock_height,omitempty"`
+	SwapType   SwapType `protobuf:"varint,5,opt,name=swap_type,proto3,enum=submarineswaprpc.SwapType" json:"swap_type,omitempty"`
+	// Amount, in satoshis, of the invoice the swap pays. Deposits are
+	// reconciled against it; zero leaves the swap amount open.
+	Amount int64 `protobuf:"varint,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	// Address receiving the deposits in excess of amount plus fees, returned
+	// with the redeem transaction. Without it the excess is kept.
+	RefundAddress string `protobuf:"bytes,7,opt,name=refund_address,proto3" json:"refund_address,omitempty"`
+	// Quote returned by GetQuote locking the swap pricing. The quote amount
+	// is used when amount is zero, otherwise both must match.
+	QuoteId []byte `protobuf:"bytes,8,opt,name=quote_id,proto3" json:"quote_id,omitempty"`
 }
 
 func (x *SubSwapServiceInitRequest) Reset() {
@@ -75,14 +141,59 @@ func (x *SubSwapServiceInitRequest) GetPubkey() []byte {
 	return nil
 }
 
+func (x *SubSwapServiceInitRequest) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *SubSwapServiceInitRequest) GetLockHeight() int64 {
+	if x != nil {
+		return x.LockHeight
+	}
+	return 0
+}
+
+func (x *SubSwapServiceInitRequest) GetSwapType() SwapType {
+	if x != nil {
+		return x.SwapType
+	}
+	return SwapType_CSV
+}
+
+func (x *SubSwapServiceInitRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *SubSwapServiceInitRequest) GetRefundAddress() string {
+	if x != nil {
+		return x.RefundAddress
+	}
+	return ""
+}
+
+func (x *SubSwapServiceInitRequest) GetQuoteId() []byte {
+	if x != nil {
+		return x.QuoteId
+	}
+	return nil
+}
+
 type SubSwapServiceInitResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Address    string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
-	Pubkey     []byte `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
-	LockHeight int64  `protobuf:"varint,3,opt,name=lock_height,proto3" json:"lock_height,omitempty"`
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Pubkey  []byte `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	// The CSV delay, or CLTV refund height, actually used in the swap script.
+	LockHeight int64 `protobuf:"varint,3,opt,name=lock_height,proto3" json:"lock_height,omitempty"`
+	// The witness script behind address, see the swapscript package.
+	Script []byte `protobuf:"bytes,4,opt,name=script,proto3" json:"script,omitempty"`
 }
 
 func (x *SubSwapServiceInitResponse) Reset() {
@@ -138,33 +249,592 @@ func (x *SubSwapServiceInitResponse) GetLockHeight() int64 {
 	return 0
 }
 
+func (x *SubSwapServiceInitResponse) GetScript() []byte {
+	if x != nil {
+		return x.Script
+	}
+	return nil
+}
+
+type SubSwapServiceProbeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// sha256("probing-01:" || hash) of the swap payment hash.
+	ProbingHash []byte `protobuf:"bytes,1,opt,name=probing_hash,proto3" json:"probing_hash,omitempty"`
+	Network     string `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`
+}
+
+func (x *SubSwapServiceProbeRequest) Reset() {
+	*x = SubSwapServiceProbeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_submarineswap_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubSwapServiceProbeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubSwapServiceProbeRequest) ProtoMessage() {}
+
+func (x *SubSwapServiceProbeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_submarineswap_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubSwapServiceProbeRequest.ProtoReflect.Descriptor instead.
+func (*SubSwapServiceProbeRequest) Descriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SubSwapServiceProbeRequest) GetProbingHash() []byte {
+	if x != nil {
+		return x.ProbingHash
+	}
+	return nil
+}
+
+func (x *SubSwapServiceProbeRequest) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+type SubSwapServiceProbeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Exists bool `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+}
+
+func (x *SubSwapServiceProbeResponse) Reset() {
+	*x = SubSwapServiceProbeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_submarineswap_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubSwapServiceProbeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubSwapServiceProbeResponse) ProtoMessage() {}
+
+func (x *SubSwapServiceProbeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_submarineswap_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubSwapServiceProbeResponse.ProtoReflect.Descriptor instead.
+func (*SubSwapServiceProbeResponse) Descriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SubSwapServiceProbeResponse) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+type SubSwapServiceScriptRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash    []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Network string `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`
+}
+
+func (x *SubSwapServiceScriptRequest) Reset() {
+	*x = SubSwapServiceScriptRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_submarineswap_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubSwapServiceScriptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubSwapServiceScriptRequest) ProtoMessage() {}
+
+func (x *SubSwapServiceScriptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_submarineswap_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubSwapServiceScriptRequest.ProtoReflect.Descriptor instead.
+func (*SubSwapServiceScriptRequest) Descriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SubSwapServiceScriptRequest) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *SubSwapServiceScriptRequest) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+type SubSwapServiceScriptResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Script []byte `protobuf:"bytes,1,opt,name=script,proto3" json:"script,omitempty"`
+}
+
+func (x *SubSwapServiceScriptResponse) Reset() {
+	*x = SubSwapServiceScriptResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_submarineswap_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubSwapServiceScriptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubSwapServiceScriptResponse) ProtoMessage() {}
+
+func (x *SubSwapServiceScriptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_submarineswap_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubSwapServiceScriptResponse.ProtoReflect.Descriptor instead.
+func (*SubSwapServiceScriptResponse) Descriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SubSwapServiceScriptResponse) GetScript() []byte {
+	if x != nil {
+		return x.Script
+	}
+	return nil
+}
+
+type GetQuoteRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Network string `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	// Invoice amount in satoshis.
+	Amount int64 `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+}
+
+func (x *GetQuoteRequest) Reset() {
+	*x = GetQuoteRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_submarineswap_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetQuoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuoteRequest) ProtoMessage() {}
+
+func (x *GetQuoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_submarineswap_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuoteRequest.ProtoReflect.Descriptor instead.
+func (*GetQuoteRequest) Descriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetQuoteRequest) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *GetQuoteRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+type GetQuoteResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	QuoteId    []byte `protobuf:"bytes,1,opt,name=quote_id,proto3" json:"quote_id,omitempty"`
+	Amount     int64  `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	ServiceFee int64  `protobuf:"varint,3,opt,name=service_fee,proto3" json:"service_fee,omitempty"`
+	// Miner fee rate, in sat/vbyte, charged for the redeem transaction.
+	FeeRate int64 `protobuf:"varint,4,opt,name=fee_rate,proto3" json:"fee_rate,omitempty"`
+	// Miner fee of a redeem transaction spending a single deposit.
+	MinerFee int64 `protobuf:"varint,5,opt,name=miner_fee,proto3" json:"miner_fee,omitempty"`
+	// What the payer deposits: amount, service_fee and miner_fee.
+	Total int64 `protobuf:"varint,6,opt,name=total,proto3" json:"total,omitempty"`
+	// Unix time after which the quote can't be used anymore.
+	Expiry int64 `protobuf:"varint,7,opt,name=expiry,proto3" json:"expiry,omitempty"`
+}
+
+func (x *GetQuoteResponse) Reset() {
+	*x = GetQuoteResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_submarineswap_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetQuoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuoteResponse) ProtoMessage() {}
+
+func (x *GetQuoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_submarineswap_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuoteResponse.ProtoReflect.Descriptor instead.
+func (*GetQuoteResponse) Descriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetQuoteResponse) GetQuoteId() []byte {
+	if x != nil {
+		return x.QuoteId
+	}
+	return nil
+}
+
+func (x *GetQuoteResponse) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *GetQuoteResponse) GetServiceFee() int64 {
+	if x != nil {
+		return x.ServiceFee
+	}
+	return 0
+}
+
+func (x *GetQuoteResponse) GetFeeRate() int64 {
+	if x != nil {
+		return x.FeeRate
+	}
+	return 0
+}
+
+func (x *GetQuoteResponse) GetMinerFee() int64 {
+	if x != nil {
+		return x.MinerFee
+	}
+	return 0
+}
+
+func (x *GetQuoteResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *GetQuoteResponse) GetExpiry() int64 {
+	if x != nil {
+		return x.Expiry
+	}
+	return 0
+}
+
+type GetLimitsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Network string `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+}
+
+func (x *GetLimitsRequest) Reset() {
+	*x = GetLimitsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_submarineswap_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLimitsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLimitsRequest) ProtoMessage() {}
+
+func (x *GetLimitsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_submarineswap_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLimitsRequest.ProtoReflect.Descriptor instead.
+func (*GetLimitsRequest) Descriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetLimitsRequest) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+type GetLimitsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Smallest swap amount accepted, in satoshis.
+	MinAmount int64 `protobuf:"varint,1,opt,name=min_amount,proto3" json:"min_amount,omitempty"`
+	// Largest swap amount that can be served now, in satoshis. It is capped
+	// by the outbound liquidity of the node paying the invoices.
+	MaxAmount int64 `protobuf:"varint,2,opt,name=max_amount,proto3" json:"max_amount,omitempty"`
+}
+
+func (x *GetLimitsResponse) Reset() {
+	*x = GetLimitsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_submarineswap_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLimitsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLimitsResponse) ProtoMessage() {}
+
+func (x *GetLimitsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_submarineswap_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLimitsResponse.ProtoReflect.Descriptor instead.
+func (*GetLimitsResponse) Descriptor() ([]byte, []int) {
+	return file_submarineswap_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetLimitsResponse) GetMinAmount() int64 {
+	if x != nil {
+		return x.MinAmount
+	}
+	return 0
+}
+
+func (x *GetLimitsResponse) GetMaxAmount() int64 {
+	if x != nil {
+		return x.MaxAmount
+	}
+	return 0
+}
+
 var File_submarineswap_proto protoreflect.FileDescriptor
 
 var file_submarineswap_proto_rawDesc = []byte{
 	0x0a, 0x13, 0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x2e,
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x10, 0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65,
-	0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x22, 0x47, 0x0a, 0x19, 0x53, 0x75, 0x62, 0x53, 0x77,
-	0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e, 0x69, 0x74, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x0c, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b,
-	0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79,
-	0x22, 0x70, 0x0a, 0x1a, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69,
-	0x63, 0x65, 0x49, 0x6e, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18,
-	0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b,
-	0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79,
-	0x12, 0x20, 0x0a, 0x0b, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x68, 0x65, 0x69, 0x67,
-	0x68, 0x74, 0x32, 0x85, 0x01, 0x0a, 0x10, 0x53, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65,
-	0x53, 0x77, 0x61, 0x70, 0x70, 0x65, 0x72, 0x12, 0x71, 0x0a, 0x12, 0x53, 0x75, 0x62, 0x53, 0x77,
-	0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e, 0x69, 0x74, 0x12, 0x2b, 0x2e,
+	0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x22, 0x99, 0x02, 0x0a, 0x19, 0x53, 0x75, 0x62, 0x53,
+	0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e, 0x69, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x75, 0x62,
+	0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65,
+	0x79, 0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x20, 0x0a, 0x0b, 0x6c,
+	0x6f, 0x63, 0x6b, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0b, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x38, 0x0a,
+	0x09, 0x73, 0x77, 0x61, 0x70, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x1a, 0x2e, 0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70,
+	0x72, 0x70, 0x63, 0x2e, 0x53, 0x77, 0x61, 0x70, 0x54, 0x79, 0x70, 0x65, 0x52, 0x09, 0x73, 0x77,
+	0x61, 0x70, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12,
+	0x26, 0x0a, 0x0e, 0x72, 0x65, 0x66, 0x75, 0x6e, 0x64, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x72, 0x65, 0x66, 0x75, 0x6e, 0x64, 0x5f,
+	0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x71, 0x75, 0x6f, 0x74, 0x65,
+	0x5f, 0x69, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x71, 0x75, 0x6f, 0x74, 0x65,
+	0x5f, 0x69, 0x64, 0x22, 0x88, 0x01, 0x0a, 0x1a, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x16, 0x0a, 0x06,
+	0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x70, 0x75,
+	0x62, 0x6b, 0x65, 0x79, 0x12, 0x20, 0x0a, 0x0b, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x68, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x6c, 0x6f, 0x63, 0x6b, 0x5f,
+	0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x22, 0x5a,
+	0x0a, 0x1a, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x0c,
+	0x70, 0x72, 0x6f, 0x62, 0x69, 0x6e, 0x67, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x0c, 0x70, 0x72, 0x6f, 0x62, 0x69, 0x6e, 0x67, 0x5f, 0x68, 0x61, 0x73, 0x68,
+	0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x22, 0x35, 0x0a, 0x1b, 0x53, 0x75,
+	0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x50, 0x72, 0x6f, 0x62,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x78, 0x69,
+	0x73, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x65, 0x78, 0x69, 0x73, 0x74,
+	0x73, 0x22, 0x4b, 0x0a, 0x1b, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x53, 0x63, 0x72, 0x69, 0x70, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04,
+	0x68, 0x61, 0x73, 0x68, 0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x22, 0x36,
+	0x0a, 0x1c, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x53, 0x63, 0x72, 0x69, 0x70, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06,
+	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x22, 0x43, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x51, 0x75, 0x6f,
+	0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74,
+	0x77, 0x6f, 0x72, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77,
+	0x6f, 0x72, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0xd0, 0x01, 0x0a, 0x10,
+	0x47, 0x65, 0x74, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x71, 0x75, 0x6f, 0x74, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x08, 0x71, 0x75, 0x6f, 0x74, 0x65, 0x5f, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06,
+	0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x61, 0x6d,
+	0x6f, 0x75, 0x6e, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f,
+	0x66, 0x65, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x5f, 0x66, 0x65, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x65, 0x65, 0x5f, 0x72, 0x61,
+	0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x66, 0x65, 0x65, 0x5f, 0x72, 0x61,
+	0x74, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x66, 0x65, 0x65, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6d, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x66, 0x65, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x78, 0x70, 0x69, 0x72, 0x79,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x65, 0x78, 0x70, 0x69, 0x72, 0x79, 0x22, 0x2c,
+	0x0a, 0x10, 0x47, 0x65, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x22, 0x53, 0x0a, 0x11,
+	0x47, 0x65, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x69, 0x6e, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x6d, 0x69, 0x6e, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x61, 0x78, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x6d, 0x61, 0x78, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x2a, 0x1d, 0x0a, 0x08, 0x53, 0x77, 0x61, 0x70, 0x54, 0x79, 0x70, 0x65, 0x12, 0x07, 0x0a,
+	0x03, 0x43, 0x53, 0x56, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x43, 0x4c, 0x54, 0x56, 0x10, 0x01,
+	0x32, 0xa1, 0x04, 0x0a, 0x10, 0x53, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x53, 0x77,
+	0x61, 0x70, 0x70, 0x65, 0x72, 0x12, 0x71, 0x0a, 0x12, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e, 0x69, 0x74, 0x12, 0x2b, 0x2e, 0x73, 0x75,
+	0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x2e, 0x53,
+	0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e, 0x69,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2c, 0x2e, 0x73, 0x75, 0x62, 0x6d, 0x61,
+	0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x75, 0x62, 0x53,
+	0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e, 0x69, 0x74, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x74, 0x0a, 0x13, 0x53, 0x75, 0x62, 0x53,
+	0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x12,
+	0x2c, 0x2e, 0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72,
+	0x70, 0x63, 0x2e, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2d, 0x2e,
+	0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63,
+	0x2e, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x50,
+	0x72, 0x6f, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x77,
+	0x0a, 0x14, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x53, 0x63, 0x72, 0x69, 0x70, 0x74, 0x12, 0x2d, 0x2e, 0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69,
+	0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61,
+	0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x63, 0x72, 0x69, 0x70, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2e, 0x2e, 0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e,
+	0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x63, 0x72, 0x69, 0x70, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x53, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x51, 0x75,
+	0x6f, 0x74, 0x65, 0x12, 0x21, 0x2e, 0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73,
+	0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x2e, 0x47, 0x65, 0x74, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69,
+	0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x2e, 0x47, 0x65, 0x74, 0x51, 0x75, 0x6f,
+	0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x56, 0x0a, 0x09,
+	0x47, 0x65, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x12, 0x22, 0x2e, 0x73, 0x75, 0x62, 0x6d,
+	0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x2e, 0x47, 0x65, 0x74,
+	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e,
+	0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63,
+	0x2e, 0x47, 0x65, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x42, 0x1a, 0x5a, 0x18, 0x73, 0x77, 0x61, 0x70, 0x70, 0x65, 0x72, 0x2f,
 	0x73, 0x75, 0x62, 0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63,
-	0x2e, 0x53, 0x75, 0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49,
-	0x6e, 0x69, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2c, 0x2e, 0x73, 0x75, 0x62,
-	0x6d, 0x61, 0x72, 0x69, 0x6e, 0x65, 0x73, 0x77, 0x61, 0x70, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x75,
-	0x62, 0x53, 0x77, 0x61, 0x70, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x49, 0x6e, 0x69, 0x74,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x03, 0x5a, 0x01, 0x2e, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -179,19 +849,38 @@ func file_submarineswap_proto_rawDescGZIP() []byte {
 	return file_submarineswap_proto_rawDescData
 }
 
-var file_submarineswap_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_submarineswap_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_submarineswap_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_submarineswap_proto_goTypes = []interface{}{
-	(*SubSwapServiceInitRequest)(nil),  // 0: submarineswaprpc.SubSwapServiceInitRequest
-	(*SubSwapServiceInitResponse)(nil), // 1: submarineswaprpc.SubSwapServiceInitResponse
+	(SwapType)(0),                        // 0: submarineswaprpc.SwapType
+	(*SubSwapServiceInitRequest)(nil),    // 1: submarineswaprpc.SubSwapServiceInitRequest
+	(*SubSwapServiceInitResponse)(nil),   // 2: submarineswaprpc.SubSwapServiceInitResponse
+	(*SubSwapServiceProbeRequest)(nil),   // 3: submarineswaprpc.SubSwapServiceProbeRequest
+	(*SubSwapServiceProbeResponse)(nil),  // 4: submarineswaprpc.SubSwapServiceProbeResponse
+	(*SubSwapServiceScriptRequest)(nil),  // 5: submarineswaprpc.SubSwapServiceScriptRequest
+	(*SubSwapServiceScriptResponse)(nil), // 6: submarineswaprpc.SubSwapServiceScriptResponse
+	(*GetQuoteRequest)(nil),              // 7: submarineswaprpc.GetQuoteRequest
+	(*GetQuoteResponse)(nil),             // 8: submarineswaprpc.GetQuoteResponse
+	(*GetLimitsRequest)(nil),             // 9: submarineswaprpc.GetLimitsRequest
+	(*GetLimitsResponse)(nil),            // 10: submarineswaprpc.GetLimitsResponse
 }
 var file_submarineswap_proto_depIdxs = []int32{
-	0, // 0: submarineswaprpc.SubmarineSwapper.SubSwapServiceInit:input_type -> submarineswaprpc.SubSwapServiceInitRequest
-	1, // 1: submarineswaprpc.SubmarineSwapper.SubSwapServiceInit:output_type -> submarineswaprpc.SubSwapServiceInitResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: submarineswaprpc.SubSwapServiceInitRequest.swap_type:type_name -> submarineswaprpc.SwapType
+	1,  // 1: submarineswaprpc.SubmarineSwapper.SubSwapServiceInit:input_type -> submarineswaprpc.SubSwapServiceInitRequest
+	3,  // 2: submarineswaprpc.SubmarineSwapper.SubSwapServiceProbe:input_type -> submarineswaprpc.SubSwapServiceProbeRequest
+	5,  // 3: submarineswaprpc.SubmarineSwapper.SubSwapServiceScript:input_type -> submarineswaprpc.SubSwapServiceScriptRequest
+	7,  // 4: submarineswaprpc.SubmarineSwapper.GetQuote:input_type -> submarineswaprpc.GetQuoteRequest
+	9,  // 5: submarineswaprpc.SubmarineSwapper.GetLimits:input_type -> submarineswaprpc.GetLimitsRequest
+	2,  // 6: submarineswaprpc.SubmarineSwapper.SubSwapServiceInit:output_type -> submarineswaprpc.SubSwapServiceInitResponse
+	4,  // 7: submarineswaprpc.SubmarineSwapper.SubSwapServiceProbe:output_type -> submarineswaprpc.SubSwapServiceProbeResponse
+	6,  // 8: submarineswaprpc.SubmarineSwapper.SubSwapServiceScript:output_type -> submarineswaprpc.SubSwapServiceScriptResponse
+	8,  // 9: submarineswaprpc.SubmarineSwapper.GetQuote:output_type -> submarineswaprpc.GetQuoteResponse
+	10, // 10: submarineswaprpc.SubmarineSwapper.GetLimits:output_type -> submarineswaprpc.GetLimitsResponse
+	6,  // [6:11] is the sub-list for method output_type
+	1,  // [1:6] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_submarineswap_proto_init() }
@@ -224,19 +913,116 @@ func file_submarineswap_proto_init() {
 				return nil
 			}
 		}
+		file_submarineswap_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubSwapServiceProbeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_submarineswap_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubSwapServiceProbeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_submarineswap_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubSwapServiceScriptRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_submarineswap_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubSwapServiceScriptResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_submarineswap_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetQuoteRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_submarineswap_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetQuoteResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_submarineswap_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetLimitsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_submarineswap_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetLimitsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_submarineswap_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_submarineswap_proto_goTypes,
 		DependencyIndexes: file_submarineswap_proto_depIdxs,
+		EnumInfos:         file_submarineswap_proto_enumTypes,
 		MessageInfos:      file_submarineswap_proto_msgTypes,
 	}.Build()
 	File_submarineswap_proto = out.File
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.2
+// source: submarineswap.proto
+
+package submarineswaprpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// SubmarineSwapperClient is the client API for SubmarineSwapper service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SubmarineSwapperClient interface {
+	SubSwapServiceInit(ctx context.Context, in *SubSwapServiceInitRequest, opts ...grpc.CallOption) (*SubSwapServiceInitResponse, error)
+	SubSwapServiceProbe(ctx context.Context, in *SubSwapServiceProbeRequest, opts ...grpc.CallOption) (*SubSwapServiceProbeResponse, error)
+	SubSwapServiceScript(ctx context.Context, in *SubSwapServiceScriptRequest, opts ...grpc.CallOption) (*SubSwapServiceScriptResponse, error)
+	GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*GetQuoteResponse, error)
+	GetLimits(ctx context.Context, in *GetLimitsRequest, opts ...grpc.CallOption) (*GetLimitsResponse, error)
+}
+
+type submarineSwapperClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSubmarineSwapperClient(cc grpc.ClientConnInterface) SubmarineSwapperClient {
+	return &submarineSwapperClient{cc}
+}
+
+func (c *submarineSwapperClient) SubSwapServiceInit(ctx context.Context, in *SubSwapServiceInitRequest, opts ...grpc.CallOption) (*SubSwapServiceInitResponse, error) {
+	out := new(SubSwapServiceInitResponse)
+	err := c.cc.Invoke(ctx, "/submarineswaprpc.SubmarineSwapper/SubSwapServiceInit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *submarineSwapperClient) SubSwapServiceProbe(ctx context.Context, in *SubSwapServiceProbeRequest, opts ...grpc.CallOption) (*SubSwapServiceProbeResponse, error) {
+	out := new(SubSwapServiceProbeResponse)
+	err := c.cc.Invoke(ctx, "/submarineswaprpc.SubmarineSwapper/SubSwapServiceProbe", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *submarineSwapperClient) SubSwapServiceScript(ctx context.Context, in *SubSwapServiceScriptRequest, opts ...grpc.CallOption) (*SubSwapServiceScriptResponse, error) {
+	out := new(SubSwapServiceScriptResponse)
+	err := c.cc.Invoke(ctx, "/submarineswaprpc.SubmarineSwapper/SubSwapServiceScript", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *submarineSwapperClient) GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*GetQuoteResponse, error) {
+	out := new(GetQuoteResponse)
+	err := c.cc.Invoke(ctx, "/submarineswaprpc.SubmarineSwapper/GetQuote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *submarineSwapperClient) GetLimits(ctx context.Context, in *GetLimitsRequest, opts ...grpc.CallOption) (*GetLimitsResponse, error) {
+	out := new(GetLimitsResponse)
+	err := c.cc.Invoke(ctx, "/submarineswaprpc.SubmarineSwapper/GetLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SubmarineSwapperServer is the server API for SubmarineSwapper service.
+// All implementations must embed UnimplementedSubmarineSwapperServer
+// for forward compatibility
+type SubmarineSwapperServer interface {
+	SubSwapServiceInit(context.Context, *SubSwapServiceInitRequest) (*SubSwapServiceInitResponse, error)
+	SubSwapServiceProbe(context.Context, *SubSwapServiceProbeRequest) (*SubSwapServiceProbeResponse, error)
+	SubSwapServiceScript(context.Context, *SubSwapServiceScriptRequest) (*SubSwapServiceScriptResponse, error)
+	GetQuote(context.Context, *GetQuoteRequest) (*GetQuoteResponse, error)
+	GetLimits(context.Context, *GetLimitsRequest) (*GetLimitsResponse, error)
+	mustEmbedUnimplementedSubmarineSwapperServer()
+}
+
+// UnimplementedSubmarineSwapperServer must be embedded to have forward compatible implementations.
+type UnimplementedSubmarineSwapperServer struct {
+}
+
+func (UnimplementedSubmarineSwapperServer) SubSwapServiceInit(context.Context, *SubSwapServiceInitRequest) (*SubSwapServiceInitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubSwapServiceInit not implemented")
+}
+func (UnimplementedSubmarineSwapperServer) SubSwapServiceProbe(context.Context, *SubSwapServiceProbeRequest) (*SubSwapServiceProbeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubSwapServiceProbe not implemented")
+}
+func (UnimplementedSubmarineSwapperServer) SubSwapServiceScript(context.Context, *SubSwapServiceScriptRequest) (*SubSwapServiceScriptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubSwapServiceScript not implemented")
+}
+func (UnimplementedSubmarineSwapperServer) GetQuote(context.Context, *GetQuoteRequest) (*GetQuoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuote not implemented")
+}
+func (UnimplementedSubmarineSwapperServer) GetLimits(context.Context, *GetLimitsRequest) (*GetLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLimits not implemented")
+}
+func (UnimplementedSubmarineSwapperServer) mustEmbedUnimplementedSubmarineSwapperServer() {}
+
+// UnsafeSubmarineSwapperServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SubmarineSwapperServer will
+// result in compilation errors.
+type UnsafeSubmarineSwapperServer interface {
+	mustEmbedUnimplementedSubmarineSwapperServer()
+}
+
+func RegisterSubmarineSwapperServer(s grpc.ServiceRegistrar, srv SubmarineSwapperServer) {
+	s.RegisterService(&SubmarineSwapper_ServiceDesc, srv)
+}
+
+func _SubmarineSwapper_SubSwapServiceInit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubSwapServiceInitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubmarineSwapperServer).SubSwapServiceInit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/submarineswaprpc.SubmarineSwapper/SubSwapServiceInit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubmarineSwapperServer).SubSwapServiceInit(ctx, req.(*SubSwapServiceInitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubmarineSwapper_SubSwapServiceProbe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubSwapServiceProbeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubmarineSwapperServer).SubSwapServiceProbe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/submarineswaprpc.SubmarineSwapper/SubSwapServiceProbe",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubmarineSwapperServer).SubSwapServiceProbe(ctx, req.(*SubSwapServiceProbeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubmarineSwapper_SubSwapServiceScript_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubSwapServiceScriptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubmarineSwapperServer).SubSwapServiceScript(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/submarineswaprpc.SubmarineSwapper/SubSwapServiceScript",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubmarineSwapperServer).SubSwapServiceScript(ctx, req.(*SubSwapServiceScriptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubmarineSwapper_GetQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubmarineSwapperServer).GetQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/submarineswaprpc.SubmarineSwapper/GetQuote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubmarineSwapperServer).GetQuote(ctx, req.(*GetQuoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubmarineSwapper_GetLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubmarineSwapperServer).GetLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/submarineswaprpc.SubmarineSwapper/GetLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubmarineSwapperServer).GetLimits(ctx, req.(*GetLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SubmarineSwapper_ServiceDesc is the grpc.ServiceDesc for SubmarineSwapper service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SubmarineSwapper_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "submarineswaprpc.SubmarineSwapper",
+	HandlerType: (*SubmarineSwapperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubSwapServiceInit",
+			Handler:    _SubmarineSwapper_SubSwapServiceInit_Handler,
+		},
+		{
+			MethodName: "SubSwapServiceProbe",
+			Handler:    _SubmarineSwapper_SubSwapServiceProbe_Handler,
+		},
+		{
+			MethodName: "SubSwapServiceScript",
+			Handler:    _SubmarineSwapper_SubSwapServiceScript_Handler,
+		},
+		{
+			MethodName: "GetQuote",
+			Handler:    _SubmarineSwapper_GetQuote_Handler,
+		},
+		{
+			MethodName: "GetLimits",
+			Handler:    _SubmarineSwapper_GetLimits_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "submarineswap.proto",
+}
@@ -2,29 +2,101 @@ package submarineswaprpc
 
 import (
 	"context"
-	"log"
+	"log/slog"
 
-	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/lightningnetwork/lnd/submarineswap"
+	"github.com/btcsuite/btcutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// Swapper is the swap service the RPC server delegates to.
+type Swapper interface {
+	NewSubmarineSwap(ctx context.Context, network string, pubKey, hash []byte, typ SwapType, requestedLockHeight int64, amount int64, refundAddress string, quoteID []byte) (address btcutil.Address, script, swapperPubKey []byte, lockHeight int64, err error)
+	ProbingHashExists(ctx context.Context, network string, probingHash []byte) (bool, error)
+	SwapScript(ctx context.Context, network string, hash []byte) ([]byte, error)
+	GetQuote(ctx context.Context, network string, amount int64) (*GetQuoteResponse, error)
+	GetLimits(ctx context.Context, network string) (min, max int64, err error)
+}
+
 // Server is a sub-server of the main RPC server.
 type Server struct {
-	ActiveNetParams *chaincfg.Params
+	UnimplementedSubmarineSwapperServer
+	Swapper Swapper
 }
 
 // SubSwapServiceInit
 func (s *Server) SubSwapServiceInit(ctx context.Context,
 	in *SubSwapServiceInitRequest) (*SubSwapServiceInitResponse, error) {
+	if in.Amount < 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount not valid")
+	}
 	//Create a new submarine address and associated script
-	addr, script, swapServicePubKey, lockHeight, err := submarineswap.NewSubmarineSwap(
-		s.ActiveNetParams,
+	addr, script, swapServicePubKey, lockHeight, err := s.Swapper.NewSubmarineSwap(
+		ctx,
+		in.Network,
 		in.Pubkey,
 		in.Hash,
+		in.SwapType,
+		in.LockHeight,
+		in.Amount,
+		in.RefundAddress,
+		in.QuoteId,
 	)
 	if err != nil {
 		return nil, err
 	}
-	log.Infof("[SubSwapServiceInit] addr=%v script=%x pubkey=%x", addr.String(), script, swapServicePubKey)
-	return &SubSwapServiceInitResponse{Address: addr.String(), Pubkey: swapServicePubKey, LockHeight: lockHeight}, nil
+	slog.Info("SubSwapServiceInit", "network", in.Network, "hash", in.Hash, "address", addr.String(),
+		"script", script, "pubkey", swapServicePubKey, "type", in.SwapType, "lock_height", lockHeight)
+	return &SubSwapServiceInitResponse{Address: addr.String(), Pubkey: swapServicePubKey, LockHeight: lockHeight, Script: script}, nil
+}
+
+// SubSwapServiceProbe reports whether a swap exists for the given probing
+// hash, without the caller having to reveal the payment hash itself.
+func (s *Server) SubSwapServiceProbe(ctx context.Context,
+	in *SubSwapServiceProbeRequest) (*SubSwapServiceProbeResponse, error) {
+	if len(in.ProbingHash) != 32 {
+		return nil, status.Error(codes.InvalidArgument, "probing hash not valid")
+	}
+	exists, err := s.Swapper.ProbingHashExists(ctx, in.Network, in.ProbingHash)
+	if err != nil {
+		return nil, err
+	}
+	return &SubSwapServiceProbeResponse{Exists: exists}, nil
+}
+
+// SubSwapServiceScript returns the witness script of the swap with the given
+// payment hash, so that wallets can verify the swap address themselves.
+func (s *Server) SubSwapServiceScript(ctx context.Context,
+	in *SubSwapServiceScriptRequest) (*SubSwapServiceScriptResponse, error) {
+	if len(in.Hash) != 32 {
+		return nil, status.Error(codes.InvalidArgument, "hash not valid")
+	}
+	script, err := s.Swapper.SwapScript(ctx, in.Network, in.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if script == nil {
+		return nil, status.Error(codes.NotFound, "swap not found")
+	}
+	return &SubSwapServiceScriptResponse{Script: script}, nil
+}
+
+// GetQuote prices a swap of the given amount. The returned quote can be
+// passed to SubSwapServiceInit until it expires.
+func (s *Server) GetQuote(ctx context.Context,
+	in *GetQuoteRequest) (*GetQuoteResponse, error) {
+	if in.Amount <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount not valid")
+	}
+	return s.Swapper.GetQuote(ctx, in.Network, in.Amount)
+}
+
+// GetLimits returns the range of swap amounts that can be served now.
+func (s *Server) GetLimits(ctx context.Context,
+	in *GetLimitsRequest) (*GetLimitsResponse, error) {
+	min, max, err := s.Swapper.GetLimits(ctx, in.Network)
+	if err != nil {
+		return nil, err
+	}
+	return &GetLimitsResponse{MinAmount: min, MaxAmount: max}, nil
 }
@@ -0,0 +1,54 @@
+package swapscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Path is the branch of a swap script taken by a spending input.
+type Path int16
+
+const (
+	// ClaimPath reveals the preimage and is signed by the swapper.
+	ClaimPath Path = 1
+	// RefundPath waits for the timelock and is signed by the payer.
+	RefundPath Path = 2
+)
+
+func (p Path) String() string {
+	switch p {
+	case ClaimPath:
+		return "claim"
+	case RefundPath:
+		return "refund"
+	}
+	return fmt.Sprintf("Path(%d)", int16(p))
+}
+
+// ClassifyWitness returns the path taken by an input spending script with
+// witness, and the preimage if the claim path was taken. The witness must be
+// <sig> <preimage> <script>; any element not hashing to the script hash,
+// usually the empty one, selects the refund path.
+func ClassifyWitness(witness wire.TxWitness, script []byte) (Path, []byte, error) {
+	if len(witness) != 3 {
+		return 0, nil, fmt.Errorf("witness has %v elements, want 3", len(witness))
+	}
+	if !bytes.Equal(witness[2], script) {
+		return 0, nil, errors.New("witness doesn't spend the swap script")
+	}
+	s, err := Parse(script)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	preimage := witness[1]
+	hash := sha256.Sum256(preimage)
+	if !s.MatchesHash(hash[:]) {
+		return RefundPath, nil, nil
+	}
+	return ClaimPath, preimage, nil
+}
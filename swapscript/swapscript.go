@@ -0,0 +1,276 @@
+// Package swapscript builds, parses and verifies the witness scripts of
+// submarine swaps, so that wallets can check the address they are given
+// before paying to it.
+//
+// A swap script is
+//
+//	OP_HASH160 <RIPEMD160(hash)> OP_EQUAL
+//	OP_IF
+//	    <swapperPubKey>
+//	OP_ELSE
+//	    <lockHeight> OP_CHECKSEQUENCEVERIFY|OP_CHECKLOCKTIMEVERIFY OP_DROP
+//	    <payerPubKey>
+//	OP_ENDIF
+//	OP_CHECKSIG
+//
+// where hash is the payment hash, i.e. SHA256 of the preimage.
+package swapscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Type selects the timelock used by the refund path of a swap script.
+type Type int16
+
+const (
+	// CSV refunds LockHeight blocks after the deposit confirmed.
+	CSV Type = 0
+	// CLTV refunds once the chain reaches the absolute height LockHeight.
+	CLTV Type = 1
+)
+
+func (t Type) String() string {
+	switch t {
+	case CSV:
+		return "csv"
+	case CLTV:
+		return "cltv"
+	}
+	return fmt.Sprintf("Type(%d)", int16(t))
+}
+
+func (t Type) lockOp() (byte, error) {
+	switch t {
+	case CSV:
+		return txscript.OP_CHECKSEQUENCEVERIFY, nil
+	case CLTV:
+		return txscript.OP_CHECKLOCKTIMEVERIFY, nil
+	}
+	return 0, fmt.Errorf("unknown swap type %v", t)
+}
+
+// Script holds the parameters of a swap script. The script only commits to
+// RIPEMD160 of the payment hash; use MatchesHash to check it against a hash.
+type Script struct {
+	Type          Type
+	HashRipemd160 []byte
+	SwapperPubKey []byte
+	PayerPubKey   []byte
+	LockHeight    int64
+}
+
+// New returns the swap script of the given type.
+func New(typ Type, swapperPubKey, payerPubKey, hash []byte, lockHeight int64) ([]byte, error) {
+	lockOp, err := typ.lockOp()
+	if err != nil {
+		return nil, err
+	}
+	return build(lockOp, swapperPubKey, payerPubKey, ripemd160H(hash), lockHeight)
+}
+
+func build(lockOp byte, swapperPubKey, payerPubKey, hashRipemd160 []byte, lockHeight int64) ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+
+	builder.AddOp(txscript.OP_HASH160)
+	builder.AddData(hashRipemd160)
+	builder.AddOp(txscript.OP_EQUAL) // Leaves 0P1 (true) on the stack if preimage matches
+	builder.AddOp(txscript.OP_IF)
+	builder.AddData(swapperPubKey) // Path taken if preimage matches
+	builder.AddOp(txscript.OP_ELSE)
+	builder.AddInt64(lockHeight)
+	builder.AddOp(lockOp)
+	builder.AddOp(txscript.OP_DROP)
+	builder.AddData(payerPubKey) // Refund back to payer
+	builder.AddOp(txscript.OP_ENDIF)
+	builder.AddOp(txscript.OP_CHECKSIG)
+
+	return builder.Script()
+}
+
+// Parse parses a swap witness script. It fails unless script is exactly what
+// New returns for the parsed parameters.
+func Parse(script []byte) (*Script, error) {
+	tokens, err := tokenize(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) != 12 {
+		return nil, errors.New("not a swap script")
+	}
+
+	for i, op := range map[int]byte{
+		0:  txscript.OP_HASH160,
+		2:  txscript.OP_EQUAL,
+		3:  txscript.OP_IF,
+		5:  txscript.OP_ELSE,
+		8:  txscript.OP_DROP,
+		10: txscript.OP_ENDIF,
+		11: txscript.OP_CHECKSIG,
+	} {
+		if tokens[i].op != op {
+			return nil, fmt.Errorf("not a swap script: unexpected opcode at %v", i)
+		}
+	}
+
+	s := &Script{
+		HashRipemd160: tokens[1].data,
+		SwapperPubKey: tokens[4].data,
+		PayerPubKey:   tokens[9].data,
+	}
+	switch tokens[7].op {
+	case txscript.OP_CHECKSEQUENCEVERIFY:
+		s.Type = CSV
+	case txscript.OP_CHECKLOCKTIMEVERIFY:
+		s.Type = CLTV
+	default:
+		return nil, errors.New("not a swap script: unknown timelock")
+	}
+
+	lockHeight, err := tokens[6].int64()
+	if err != nil {
+		return nil, fmt.Errorf("not a swap script: %w", err)
+	}
+	s.LockHeight = lockHeight
+
+	if len(s.HashRipemd160) != ripemd160.Size {
+		return nil, errors.New("not a swap script: hash not valid")
+	}
+	if _, err := btcec.ParsePubKey(s.SwapperPubKey, btcec.S256()); err != nil {
+		return nil, fmt.Errorf("swapper pubkey not valid: %w", err)
+	}
+	if _, err := btcec.ParsePubKey(s.PayerPubKey, btcec.S256()); err != nil {
+		return nil, fmt.Errorf("payer pubkey not valid: %w", err)
+	}
+
+	// Reject non canonical encodings so that a parsed script always maps
+	// back to the same address.
+	lockOp, _ := s.Type.lockOp()
+	canonical, err := build(lockOp, s.SwapperPubKey, s.PayerPubKey, s.HashRipemd160, s.LockHeight)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, script) {
+		return nil, errors.New("not a swap script: non canonical encoding")
+	}
+
+	return s, nil
+}
+
+// MatchesHash reports whether the script commits to the payment hash.
+func (s *Script) MatchesHash(hash []byte) bool {
+	return bytes.Equal(s.HashRipemd160, ripemd160H(hash))
+}
+
+// Address returns the P2WSH address of script on net.
+func Address(script []byte, net *chaincfg.Params) (*btcutil.AddressWitnessScriptHash, error) {
+	witnessProg := sha256.Sum256(script)
+	return btcutil.NewAddressWitnessScriptHash(witnessProg[:], net)
+}
+
+// Verify parses script and checks that address is its P2WSH address on net.
+func Verify(script []byte, address string, net *chaincfg.Params) (*Script, error) {
+	s, err := Parse(script)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := Address(script, net)
+	if err != nil {
+		return nil, err
+	}
+	if addr.EncodeAddress() != address {
+		return nil, fmt.Errorf("address %v doesn't match script address %v", address, addr.EncodeAddress())
+	}
+	return s, nil
+}
+
+type token struct {
+	op   byte
+	data []byte
+}
+
+// tokenize splits script into opcodes and their pushed data.
+func tokenize(script []byte) ([]token, error) {
+	var tokens []token
+	for len(script) > 0 {
+		op := script[0]
+		script = script[1:]
+
+		var n int
+		switch {
+		case op > txscript.OP_0 && op < txscript.OP_PUSHDATA1:
+			n = int(op)
+		case op == txscript.OP_PUSHDATA1:
+			if len(script) < 1 {
+				return nil, errors.New("malformed push")
+			}
+			n = int(script[0])
+			script = script[1:]
+		case op == txscript.OP_PUSHDATA2:
+			if len(script) < 2 {
+				return nil, errors.New("malformed push")
+			}
+			n = int(binary.LittleEndian.Uint16(script))
+			script = script[2:]
+		case op == txscript.OP_PUSHDATA4:
+			if len(script) < 4 {
+				return nil, errors.New("malformed push")
+			}
+			n = int(binary.LittleEndian.Uint32(script))
+			script = script[4:]
+		}
+		if len(script) < n {
+			return nil, errors.New("malformed push")
+		}
+		tokens = append(tokens, token{op: op, data: script[:n]})
+		script = script[n:]
+	}
+	return tokens, nil
+}
+
+// int64 decodes a number pushed by txscript.ScriptBuilder.AddInt64.
+func (t token) int64() (int64, error) {
+	switch {
+	case t.op == txscript.OP_0:
+		return 0, nil
+	case t.op == txscript.OP_1NEGATE:
+		return -1, nil
+	case t.op >= txscript.OP_1 && t.op <= txscript.OP_16:
+		return int64(t.op - (txscript.OP_1 - 1)), nil
+	case t.op > txscript.OP_0 && t.op < txscript.OP_PUSHDATA1 && len(t.data) <= 5:
+		return scriptNum(t.data), nil
+	}
+	return 0, errors.New("lock height not valid")
+}
+
+// scriptNum decodes a little endian, sign and magnitude script number.
+func scriptNum(b []byte) int64 {
+	if len(b) == 0 {
+		return 0
+	}
+	var n int64
+	for i, v := range b {
+		n |= int64(v) << uint8(8*i)
+	}
+	if b[len(b)-1]&0x80 != 0 {
+		n &= ^(int64(0x80) << uint8(8*(len(b)-1)))
+		return -n
+	}
+	return n
+}
+
+func ripemd160H(d []byte) []byte {
+	h := ripemd160.New()
+	h.Write(d)
+	return h.Sum(nil)
+}
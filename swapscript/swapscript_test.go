@@ -0,0 +1,141 @@
+package swapscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func pubKey(t *testing.T) []byte {
+	t.Helper()
+	k, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k.PubKey().SerializeCompressed()
+}
+
+func TestNewParse(t *testing.T) {
+	swapperPubKey, payerPubKey := pubKey(t), pubKey(t)
+	preimage := []byte("preimage of the swap payment hash")
+	hash := sha256.Sum256(preimage)
+
+	for _, tt := range []struct {
+		name       string
+		typ        Type
+		lockHeight int64
+	}{
+		{"csv", CSV, 288},
+		{"csv small", CSV, 1},
+		{"cltv", CLTV, 850000},
+		{"cltv large", CLTV, 499999999},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := New(tt.typ, swapperPubKey, payerPubKey, hash[:], tt.lockHeight)
+			if err != nil {
+				t.Fatalf("New() error: %v", err)
+			}
+			s, err := Parse(script)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if s.Type != tt.typ || s.LockHeight != tt.lockHeight {
+				t.Errorf("Parse() = %v %v, want %v %v", s.Type, s.LockHeight, tt.typ, tt.lockHeight)
+			}
+			if !bytes.Equal(s.SwapperPubKey, swapperPubKey) || !bytes.Equal(s.PayerPubKey, payerPubKey) {
+				t.Errorf("Parse() pubkeys don't match")
+			}
+			if !s.MatchesHash(hash[:]) {
+				t.Errorf("MatchesHash() = false")
+			}
+
+			address, err := Address(script, &chaincfg.RegressionNetParams)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Verify(script, address.EncodeAddress(), &chaincfg.RegressionNetParams); err != nil {
+				t.Errorf("Verify() error: %v", err)
+			}
+			if _, err := Verify(script, address.EncodeAddress(), &chaincfg.MainNetParams); err == nil {
+				t.Errorf("Verify() on another network succeeded")
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	hash := sha256.Sum256([]byte("preimage"))
+	script, err := New(CSV, pubKey(t), pubKey(t), hash[:], 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := script[:len(script)-1]
+	tampered := append([]byte(nil), script...)
+	// Replace OP_EQUAL with OP_EQUALVERIFY.
+	tampered[22] = 0x88
+
+	for _, tt := range []struct {
+		name   string
+		script []byte
+	}{
+		{"empty", nil},
+		{"truncated", truncated},
+		{"tampered", tampered},
+		{"trailing", append(append([]byte(nil), script...), 0x51)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.script); err == nil {
+				t.Errorf("Parse() succeeded")
+			}
+		})
+	}
+
+	if _, err := New(Type(7), pubKey(t), pubKey(t), hash[:], 144); err == nil {
+		t.Errorf("New() with unknown type succeeded")
+	}
+}
+
+func TestClassifyWitness(t *testing.T) {
+	preimage := []byte("preimage of the swap payment hash")
+	hash := sha256.Sum256(preimage)
+	sig := bytes.Repeat([]byte{1}, 72)
+
+	for _, typ := range []Type{CSV, CLTV} {
+		script, err := New(typ, pubKey(t), pubKey(t), hash[:], 144)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := New(typ, pubKey(t), pubKey(t), hash[:], 144)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range []struct {
+			name         string
+			witness      wire.TxWitness
+			wantPath     Path
+			wantPreimage []byte
+			wantErr      bool
+		}{
+			{"claim", wire.TxWitness{sig, preimage, script}, ClaimPath, preimage, false},
+			{"refund", wire.TxWitness{sig, nil, script}, RefundPath, nil, false},
+			{"refund wrong preimage", wire.TxWitness{sig, []byte("other"), script}, RefundPath, nil, false},
+			{"other script", wire.TxWitness{sig, preimage, other}, 0, nil, true},
+			{"short witness", wire.TxWitness{sig, script}, 0, nil, true},
+		} {
+			t.Run(typ.String()+" "+tt.name, func(t *testing.T) {
+				path, got, err := ClassifyWitness(tt.witness, script)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ClassifyWitness() error = %v, want error %v", err, tt.wantErr)
+				}
+				if path != tt.wantPath || !bytes.Equal(got, tt.wantPreimage) {
+					t.Errorf("ClassifyWitness() = %v %x, want %v %x", path, got, tt.wantPath, tt.wantPreimage)
+				}
+			})
+		}
+	}
+}
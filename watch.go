@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"swapper/chain"
+	"swapper/swapscript"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+)
+
+// watchNetwork consumes the chain events of n until ctx is done: deposits to
+// swap addresses are recorded and each new block triggers handleBlock. The
+// open swaps are tracked again even without a subscriber, for the providers
+// which only know about tracked addresses.
+func watchNetwork(ctx context.Context, n *network) {
+	if n.lnd != nil {
+		n.lnd.Start(ctx)
+	}
+	if n.subscriber == nil {
+		trackOpenSwaps(ctx, n)
+		return
+	}
+	go func() {
+		err := n.subscriber.Run(ctx)
+		slog.Warn("subscriber stopped", "network", n.params.Name, "error", err)
+	}()
+	trackOpenSwaps(ctx, n)
+
+	logger := slog.With("network", n.params.Name)
+	var tip *chain.Block
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b := <-n.subscriber.Blocks():
+			logger.Debug("new block", "block", b.Hash, "height", b.Height)
+			if tip != nil && (b.Height <= tip.Height ||
+				b.PrevHash != (chainhash.Hash{}) && b.Height == tip.Height+1 && b.PrevHash != tip.Hash) {
+				logger.Warn("reorg", "block", b.Hash, "height", b.Height,
+					"tip", tip.Hash, "tip_height", tip.Height)
+			}
+			tip = &b
+			handleBlock(ctx, n, b)
+		case d := <-n.subscriber.Deposits():
+			logger.Debug("deposit", "outpoint", d.OutPoint, "value", int64(d.Value), "address", d.Address,
+				"confirmed", d.Confirmed, "height", d.BlockHeight)
+			handleDeposit(ctx, n, d)
+		}
+	}
+}
+
+// swapLogger returns the default logger with the fields identifying the swap
+// hash on network.
+func swapLogger(network string, hash []byte) *slog.Logger {
+	return slog.With("network", network, "hash", hash)
+}
+
+// trackOpenSwaps tracks again the addresses of the swaps of n which may still
+// receive or spend deposits.
+func trackOpenSwaps(ctx context.Context, n *network) {
+	swaps, err := getOpenSwaps(ctx, n.params.Name)
+	if err != nil {
+		slog.Error("trackOpenSwaps failed", "network", n.params.Name, "error", err)
+		return
+	}
+	for _, s := range swaps {
+		address, err := swapscript.Address(s.script, n.params)
+		if err != nil {
+			swapLogger(n.params.Name, s.hash).Error("swap address failed", "error", err)
+			continue
+		}
+		if s.address == "" {
+			if err := setSwapAddress(ctx, n.params.Name, s.hash, address.EncodeAddress()); err != nil {
+				swapLogger(n.params.Name, s.hash).Error("setSwapAddress failed", "error", err)
+				continue
+			}
+		}
+		trackSwapAddress(n.params, address, s.created)
+	}
+}
+
+// handleBlock rolls back the confirmations reorganized out of the chain,
+// then records the spends of deposits, updates the funded state of the swaps
+// of n and expires the swaps never funded.
+func handleBlock(ctx context.Context, n *network, b chain.Block) {
+	checkReorgs(ctx, n, b.Height)
+	checkSpends(ctx, n, b.Height)
+	funded, reopened, err := updateFundedStates(ctx, n.params.Name, safeHeight(b.Height))
+	if err != nil {
+		slog.Error("updateFundedStates failed", "network", n.params.Name, "error", err)
+	}
+	observeSwapTransition(n.params.Name, swapStateFunded, funded)
+	for _, s := range reopened {
+		address, err := swapscript.Address(s.script, n.params)
+		if err != nil {
+			swapLogger(n.params.Name, s.hash).Error("swap address failed", "error", err)
+			continue
+		}
+		swapLogger(n.params.Name, s.hash).Info("expired swap funded, reopened", "address", address)
+		trackSwapAddress(n.params, address, s.created)
+	}
+	expireUnfundedSwaps(ctx, n)
+}
+
+func handleDeposit(ctx context.Context, n *network, d chain.Deposit) {
+	hash, state, err := getSwapByAddress(ctx, n.params.Name, d.Address)
+	if err != nil {
+		slog.Error("getSwapByAddress failed", "network", n.params.Name, "address", d.Address, "error", err)
+		return
+	}
+	if hash == nil {
+		slog.Warn("deposit to unknown address", "network", n.params.Name, "address", d.Address,
+			"outpoint", d.OutPoint)
+		return
+	}
+	logger := swapLogger(n.params.Name, hash).With("address", d.Address, "state", state)
+	var blockHeight int32
+	var blockHash []byte
+	if d.Confirmed {
+		blockHeight = d.BlockHeight
+		blockHash = d.BlockHash[:]
+	}
+	if err := saveSwapDeposit(ctx, n.params.Name, hash, d.OutPoint, d.Value, blockHeight, blockHash); err != nil {
+		logger.Error("saveSwapDeposit failed", "outpoint", d.OutPoint, "error", err)
+		return
+	}
+	logger.Info("deposit recorded", "outpoint", d.OutPoint, "value", int64(d.Value), "height", blockHeight)
+}
+
+// spendCheckInterval is how often, in blocks, the deposits which can only be
+// spent by a claim we didn't broadcast are looked up.
+const spendCheckInterval = 6
+
+// checkSpends looks for the spends of the recorded deposits of n, classifies
+// the script path they took and moves their swap to its final state. The
+// preimage is stored when revealed by the claim path. Only the deposits
+// expected to be spent are looked up at every block.
+func checkSpends(ctx context.Context, n *network, height int32) {
+	finder, ok := n.backend.(chain.SpendFinder)
+	if !ok {
+		return
+	}
+	deposits, err := getUnspentDeposits(ctx, n.params.Name)
+	if err != nil {
+		slog.Error("getUnspentDeposits failed", "network", n.params.Name, "error", err)
+		return
+	}
+
+	for _, d := range deposits {
+		if height%spendCheckInterval != 0 && !spendExpected(d, height) {
+			continue
+		}
+		logger := swapLogger(n.params.Name, d.hash).With("outpoint", d.outPoint)
+		spend, err := finder.GetSpend(ctx, d.outPoint)
+		if err != nil {
+			logger.Warn("GetSpend failed", "error", err)
+			continue
+		}
+		// A spend in the mempool may still be replaced.
+		if spend == nil || !spend.Confirmed {
+			continue
+		}
+
+		path, preimage, err := swapscript.ClassifyWitness(spend.Witness(), d.script)
+		if err != nil {
+			logger.Error("spend not classified", "txid", spend.Txid(), "error", err)
+			continue
+		}
+		txid := spend.Txid()
+		state := swapStateRefunded
+		if path == swapscript.ClaimPath {
+			state = swapStateClaimedByThirdParty
+			for _, redeemTxid := range d.redeemTxids {
+				if bytes.Equal(redeemTxid, txid[:]) {
+					state = swapStateClaimed
+				}
+			}
+		}
+
+		// Without a block hash the spend can't be checked for reorgs.
+		var blockHash []byte
+		if spend.BlockHash != (chainhash.Hash{}) {
+			blockHash = spend.BlockHash[:]
+		}
+
+		logger.Info("deposit spent", "txid", txid, "path", path, "state", state)
+		changed, err := setSwapSpent(ctx, n.params.Name, d.hash, d.outPoint, txid, path,
+			spend.BlockHeight, blockHash, state, preimage)
+		if err != nil {
+			logger.Error("setSwapSpent failed", "error", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		observeSwapTransition(n.params.Name, state, 1)
+		// Only the fee of the redeem transaction which confirmed is paid.
+		if state == swapStateClaimed {
+			fee, err := getRedeemFee(ctx, n.params.Name, txid)
+			if err != nil {
+				logger.Error("getRedeemFee failed", "error", err)
+				continue
+			}
+			observeRedeemFee(n.params.Name, fee)
+		}
+	}
+}
+
+// spendExpected reports whether the deposit d may be spent at height: we
+// broadcast a redeem transaction for its swap, or its refund path is open.
+func spendExpected(d unspentDeposit, height int32) bool {
+	if len(d.redeemTxids) > 0 {
+		return true
+	}
+	s, err := swapscript.Parse(d.script)
+	if err != nil {
+		return true
+	}
+	if s.Type == swapscript.CSV {
+		return d.blockHeight > 0 && int64(height) >= int64(d.blockHeight)+s.LockHeight
+	}
+	return int64(height) >= s.LockHeight
+}
+
+// trackSwapAddress asks the trackers of net for the outputs paying to
+// address, created at created.
+func trackSwapAddress(net *chaincfg.Params, address btcutil.Address, created time.Time) {
+	n, ok := networks[net.Name]
+	if !ok {
+		return
+	}
+	for _, t := range n.trackers() {
+		if err := t.TrackAddress(address.EncodeAddress(), created); err != nil {
+			slog.Error("TrackAddress failed", "network", net.Name, "address", address.EncodeAddress(), "error", err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/sha256"
+	"swapper/swapscript"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+func TestSpendExpected(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := key.PubKey().SerializeCompressed()
+	hash := sha256.Sum256([]byte("preimage"))
+	csv, err := swapscript.New(swapscript.CSV, pubKey, pubKey, hash[:], 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cltv, err := swapscript.New(swapscript.CLTV, pubKey, pubKey, hash[:], 800000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		d      unspentDeposit
+		height int32
+		want   bool
+	}{
+		{"csv locked", unspentDeposit{script: csv, blockHeight: 1000}, 1143, false},
+		{"csv open", unspentDeposit{script: csv, blockHeight: 1000}, 1144, true},
+		{"csv unconfirmed", unspentDeposit{script: csv}, 1000000, false},
+		{"cltv locked", unspentDeposit{script: cltv, blockHeight: 1000}, 799999, false},
+		{"cltv open", unspentDeposit{script: cltv, blockHeight: 1000}, 800000, true},
+		{"redeemed", unspentDeposit{script: csv, redeemTxids: [][]byte{{1}}}, 1000, true},
+		{"unparsable script", unspentDeposit{script: []byte{1}}, 1000, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spendExpected(tt.d, tt.height); got != tt.want {
+				t.Errorf("spendExpected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"swapper/chain"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// zeroConfPolicy decides which deposits seen only in the mempool may be
+// counted before they confirm. The zero value accepts none.
+type zeroConfPolicy struct {
+	// maxDeposit is the largest single deposit accepted. 0 disables zero
+	// conf deposits.
+	maxDeposit btcutil.Amount
+	// maxSwapExposure caps the sum of the unconfirmed deposits accepted for
+	// one swap.
+	maxSwapExposure btcutil.Amount
+	// minFeeRatePercent is the lowest fee rate of the funding transaction
+	// accepted, in percent of the recommended fee rate.
+	minFeeRatePercent uint64
+	// allowRBF accepts funding transactions signalling replaceability.
+	allowRBF bool
+}
+
+// loadZeroConfPolicy reads ZEROCONF_MAX_DEPOSIT and
+// ZEROCONF_MAX_SWAP_EXPOSURE (in satoshis), ZEROCONF_MIN_FEE_RATE_PERCENT
+// (default 100) and ZEROCONF_ALLOW_RBF. Zero conf deposits are disabled
+// unless ZEROCONF_MAX_DEPOSIT is set.
+func loadZeroConfPolicy(c *config) error {
+	p := zeroConfPolicy{minFeeRatePercent: 100}
+	for _, v := range []struct {
+		name  string
+		value *btcutil.Amount
+	}{
+		{"ZEROCONF_MAX_DEPOSIT", &p.maxDeposit},
+		{"ZEROCONF_MAX_SWAP_EXPOSURE", &p.maxSwapExposure},
+	} {
+		s := os.Getenv(v.name)
+		if s == "" {
+			continue
+		}
+		a, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%v=%v: %w", v.name, s, err)
+		}
+		*v.value = btcutil.Amount(a)
+	}
+	if p.maxSwapExposure == 0 {
+		p.maxSwapExposure = p.maxDeposit
+	}
+	if p.maxDeposit < 0 || p.maxSwapExposure < p.maxDeposit {
+		return fmt.Errorf("invalid zero conf limits: deposit %v, swap exposure %v",
+			p.maxDeposit, p.maxSwapExposure)
+	}
+
+	if s := os.Getenv("ZEROCONF_MIN_FEE_RATE_PERCENT"); s != "" {
+		pct, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("ZEROCONF_MIN_FEE_RATE_PERCENT=%v: %w", s, err)
+		}
+		p.minFeeRatePercent = pct
+	}
+	if s := os.Getenv("ZEROCONF_ALLOW_RBF"); s != "" {
+		allow, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("ZEROCONF_ALLOW_RBF=%v: %w", s, err)
+		}
+		p.allowRBF = allow
+	}
+
+	c.zeroConf = p
+	return nil
+}
+
+// check returns why tx, funding a deposit of value, can't be accepted before
+// it confirms, or nil. recommendedFee is in sat/vbyte.
+func (p *zeroConfPolicy) check(tx *chain.MempoolTx, value btcutil.Amount, recommendedFee uint64) error {
+	if value > p.maxDeposit {
+		return fmt.Errorf("deposit %v above %v", value, p.maxDeposit)
+	}
+	if tx.UnconfirmedParents > 0 {
+		return fmt.Errorf("%v unconfirmed parents", tx.UnconfirmedParents)
+	}
+	if tx.Replaceable && !p.allowRBF {
+		return errors.New("signals replaceability")
+	}
+	minFeeRate := float64(recommendedFee*p.minFeeRatePercent) / 100
+	if tx.FeeRate() < minFeeRate {
+		return fmt.Errorf("fee rate %.2f sat/vB below %.2f", tx.FeeRate(), minFeeRate)
+	}
+	return nil
+}
+
+// zeroConfUtxos returns the deposits to the swap hash on net accepted by the
+// zero conf policy, within its per swap exposure budget. utxos are the
+// confirmed utxos of the swap: the deposits accepted unconfirmed and since
+// confirmed among them are returned as well, so that they stay accepted until
+// they have safe depth. An accepted deposit isn't checked against the policy
+// again, even if the policy was disabled since, as the invoice may be paid
+// already: it is only dropped once gone from the mempool.
+func zeroConfUtxos(ctx context.Context, net *chaincfg.Params, hash []byte, utxos []chain.Utxo) ([]chain.Utxo, error) {
+	policy := currentConfig().zeroConf
+	c, err := chainClient(net)
+	if err != nil {
+		return nil, err
+	}
+	reader, _ := c.(chain.MempoolReader)
+	deposits, err := getZeroConfDeposits(ctx, net.Name, hash)
+	if err != nil || len(deposits) == 0 {
+		return nil, err
+	}
+	confirmed := make(map[wire.OutPoint]chain.Utxo, len(utxos))
+	for _, u := range utxos {
+		confirmed[u.OutPoint] = u
+	}
+
+	logger := swapLogger(net.Name, hash)
+	var accepted []chain.Utxo
+	var exposure btcutil.Amount
+	var recommendedFee uint64
+	for _, d := range deposits {
+		if !d.accepted {
+			continue
+		}
+		if u, ok := confirmed[d.OutPoint]; ok {
+			exposure += d.Value
+			accepted = append(accepted, u)
+			continue
+		}
+		if reader != nil {
+			tx, err := reader.GetMempoolTx(ctx, d.Hash)
+			if err != nil {
+				logger.Warn("GetMempoolTx failed", "txid", d.Hash, "error", err)
+			} else if tx == nil {
+				// Confirmed since utxos were read, or gone.
+				continue
+			}
+		}
+		exposure += d.Value
+		accepted = append(accepted, d.Utxo)
+	}
+	for _, d := range deposits {
+		if d.accepted {
+			continue
+		}
+		if _, ok := confirmed[d.OutPoint]; ok || reader == nil || policy.maxDeposit == 0 {
+			continue
+		}
+		if exposure+d.Value > policy.maxSwapExposure {
+			logger.Info("zero conf deposit rejected", "outpoint", d.OutPoint,
+				"reason", fmt.Sprintf("exposure %v above %v", exposure+d.Value, policy.maxSwapExposure))
+			continue
+		}
+		tx, err := reader.GetMempoolTx(ctx, d.Hash)
+		if err != nil {
+			logger.Warn("GetMempoolTx failed", "txid", d.Hash, "error", err)
+			continue
+		}
+		// Confirmed since utxos were read, or gone.
+		if tx == nil {
+			continue
+		}
+		if recommendedFee == 0 {
+			recommendedFee, err = c.RecommendedFee(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := policy.check(tx, d.Value, recommendedFee); err != nil {
+			logger.Info("zero conf deposit rejected", "outpoint", d.OutPoint, "reason", err)
+			continue
+		}
+		if err := setZeroConfAccepted(ctx, net.Name, d.OutPoint); err != nil {
+			return nil, err
+		}
+		exposure += d.Value
+		accepted = append(accepted, d.Utxo)
+	}
+	return accepted, nil
+}
+
+// addUtxos returns utxos with the elements of more not already in it.
+func addUtxos(utxos, more []chain.Utxo) []chain.Utxo {
+	seen := make(map[wire.OutPoint]struct{}, len(utxos))
+	for _, u := range utxos {
+		seen[u.OutPoint] = struct{}{}
+	}
+	for _, u := range more {
+		if _, ok := seen[u.OutPoint]; !ok {
+			utxos = append(utxos, u)
+		}
+	}
+	return utxos
+}